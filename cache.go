@@ -0,0 +1,20 @@
+package gobl
+
+import (
+	"github.com/kettek/gobl/pkg/cache"
+	"github.com/kettek/gobl/pkg/task"
+)
+
+// Cache sets the URL of a remote HTTP cache used to share task outputs. This overrides the GOBL_CACHE_URL environment variable. Passing an empty URL disables the remote cache.
+func Cache(url string) {
+	if url == "" {
+		task.OutputCache.Remote = nil
+		return
+	}
+	task.OutputCache.Remote = cache.NewRemote(url)
+}
+
+// CacheEnv adds environment variables whose values are included in the cache keys of tasks, such as variables read by the tools a task runs. Variables set by a task's steps are always included.
+func CacheEnv(names ...string) {
+	task.CacheEnv = append(task.CacheEnv, names...)
+}
@@ -0,0 +1,191 @@
+package gobl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kettek/gobl/pkg/task"
+)
+
+const usage = `Usage:
+  go run . [flags] [task [param=value | --param value]...]...
+  go run . help [task...]
+
+Flags:
+  -h, --help       show this help or the help of the given tasks
+  -l, --list       list tasks instead of running them
+  -a, --all        include hidden tasks when listing tasks
+      --json       list all tasks as JSON, for use with --list
+  -v, --verbose    print each step as it runs
+  -q, --quiet      only print errors and command output
+  -n, --dry-run    print what would run without running it
+      --no-color   disable colored output
+  -j, --jobs N     run at most N tasks at once
+  -f, --force      run tasks even if their outputs are up to date
+`
+
+// options are the parsed command-line arguments passed to Go.
+type options struct {
+	tasks   []*taskArgs
+	help    bool
+	list    bool
+	all     bool
+	json    bool
+	verbose bool
+	quiet   bool
+	dryRun  bool
+	noColor bool
+	force   bool
+	jobs    int
+}
+
+// taskArgs is a task to run along with the parameters to run it with.
+type taskArgs struct {
+	name   string
+	params [][2]string
+}
+
+// param returns the named parameter of the most recently given task, if any.
+func (o *options) param(name string) *task.Param {
+	if len(o.tasks) == 0 {
+		return nil
+	}
+	g := task.GetTask(o.tasks[len(o.tasks)-1].name)
+	if g == nil {
+		return nil
+	}
+	return g.GetParam(name)
+}
+
+// setParam validates and records a parameter for the most recently given task.
+func (o *options) setParam(name, value string) error {
+	if len(o.tasks) == 0 {
+		return fmt.Errorf("parameter %s=%s given before any task", name, value)
+	}
+	t := o.tasks[len(o.tasks)-1]
+	p := o.param(name)
+	if p == nil {
+		return fmt.Errorf("task \"%s\" has no parameter \"%s\"", t.name, name)
+	}
+	if _, err := p.Parse(value); err != nil {
+		return err
+	}
+	t.params = append(t.params, [2]string{name, value})
+	return nil
+}
+
+// apply sets the task's parameters.
+func (t *taskArgs) apply() error {
+	g := task.GetTask(t.name)
+	if g == nil {
+		return nil
+	}
+	for _, p := range t.params {
+		if err := g.SetParam(p[0], p[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseArgs parses command-line arguments. Flags may appear anywhere and "--" ends flag parsing. Parameters, given as "name=value" or "--name value", apply to the task preceding them, and take priority over global flags of the same name.
+func parseArgs(args []string) (*options, error) {
+	opts := &options{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			for _, name := range args[i+1:] {
+				opts.tasks = append(opts.tasks, &taskArgs{name: name})
+			}
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if arg == "help" && len(opts.tasks) == 0 && !opts.help {
+				opts.help = true
+				continue
+			}
+			if i := strings.Index(arg, "="); i > 0 {
+				if err := opts.setParam(arg[:i], arg[i+1:]); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			opts.tasks = append(opts.tasks, &taskArgs{name: arg})
+			continue
+		}
+
+		name, value, hasValue := arg, "", false
+		if i := strings.Index(arg, "="); i != -1 {
+			name, value, hasValue = arg[:i], arg[i+1:], true
+		}
+		// Allow "-j4" as shorthand for "-j 4".
+		if strings.HasPrefix(name, "-j") && len(name) > 2 && !strings.HasPrefix(name, "--") {
+			name, value, hasValue = "-j", name[2:], true
+		}
+
+		if p := opts.param(strings.TrimPrefix(name, "--")); p != nil && strings.HasPrefix(name, "--") {
+			if !hasValue {
+				if _, ok := p.Value.(*bool); ok {
+					value = "true"
+				} else if i+1 >= len(args) {
+					return nil, fmt.Errorf("parameter %s requires a value", name)
+				} else {
+					i++
+					value = args[i]
+				}
+			}
+			if err := opts.setParam(p.Name, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		switch name {
+		case "-h", "--help":
+			opts.help = true
+		case "-l", "--list":
+			opts.list = true
+		case "-a", "--all":
+			opts.all = true
+		case "--json":
+			opts.json = true
+		case "-v", "--verbose":
+			opts.verbose = true
+		case "-q", "--quiet":
+			opts.quiet = true
+		case "-n", "--dry-run":
+			opts.dryRun = true
+		case "--no-color":
+			opts.noColor = true
+		case "-f", "--force":
+			opts.force = true
+		case "-j", "--jobs":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag %s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			jobs, err := strconv.Atoi(value)
+			if err != nil || jobs < 1 {
+				return nil, fmt.Errorf("invalid value %q for flag %s", value, name)
+			}
+			opts.jobs = jobs
+			continue
+		default:
+			return nil, fmt.Errorf("unknown flag: %s", name)
+		}
+		if hasValue {
+			return nil, fmt.Errorf("flag %s does not take a value", name)
+		}
+	}
+	if opts.json && !opts.list {
+		return nil, fmt.Errorf("flag --json requires --list")
+	}
+	if opts.verbose && opts.quiet {
+		return nil, fmt.Errorf("flags --verbose and --quiet cannot be used together")
+	}
+	return opts, nil
+}
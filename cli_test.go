@@ -0,0 +1,105 @@
+package gobl
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kettek/gobl/pkg/task"
+)
+
+// withTasks replaces the registered tasks for the duration of the test.
+func withTasks(t *testing.T) {
+	tasks := task.Tasks
+	task.Tasks = nil
+	t.Cleanup(func() { task.Tasks = tasks })
+}
+
+// describeOptions returns the options with their tasks written out in full.
+func describeOptions(o *options) string {
+	var tasks []string
+	for _, t := range o.tasks {
+		tasks = append(tasks, fmt.Sprintf("%+v", *t))
+	}
+	c := *o
+	c.tasks = nil
+	return fmt.Sprintf("%+v tasks:%v", c, tasks)
+}
+
+func TestParseArgs(t *testing.T) {
+	withTasks(t)
+	Task("build").Param("target", "app").Param("race", false).Param("count", 1)
+	Task("deploy").Param("force", false).Param("jobs", 1)
+	Task("test")
+
+	tests := []struct {
+		args []string
+		want options
+	}{
+		{nil, options{}},
+		{[]string{"build", "test"}, options{tasks: []*taskArgs{{name: "build"}, {name: "test"}}}},
+		{[]string{"-v", "build", "--no-color", "-f"}, options{tasks: []*taskArgs{{name: "build"}}, verbose: true, noColor: true, force: true}},
+		{[]string{"-j4", "build"}, options{tasks: []*taskArgs{{name: "build"}}, jobs: 4}},
+		{[]string{"-j", "2", "--jobs=3"}, options{jobs: 3}},
+		{[]string{"--list", "--json", "-a"}, options{list: true, json: true, all: true}},
+		{[]string{"help"}, options{help: true}},
+		{[]string{"help", "build"}, options{help: true, tasks: []*taskArgs{{name: "build"}}}},
+		{[]string{"help", "help"}, options{help: true, tasks: []*taskArgs{{name: "help"}}}},
+		{[]string{"test", "help"}, options{tasks: []*taskArgs{{name: "test"}, {name: "help"}}}},
+		{[]string{"--", "-v", "build"}, options{tasks: []*taskArgs{{name: "-v"}, {name: "build"}}}},
+		{[]string{"build", "target=cli", "--count", "3", "--race", "test"}, options{tasks: []*taskArgs{
+			{name: "build", params: [][2]string{{"target", "cli"}, {"count", "3"}, {"race", "true"}}},
+			{name: "test"},
+		}}},
+		{[]string{"build", "--target=a=b", "--race=false"}, options{tasks: []*taskArgs{
+			{name: "build", params: [][2]string{{"target", "a=b"}, {"race", "false"}}},
+		}}},
+		// Parameters named like global flags belong to the task preceding them.
+		{[]string{"deploy", "--force", "--jobs", "2", "-f", "-j", "3"}, options{tasks: []*taskArgs{
+			{name: "deploy", params: [][2]string{{"force", "true"}, {"jobs", "2"}}},
+		}, force: true, jobs: 3}},
+		{[]string{"--force", "deploy"}, options{tasks: []*taskArgs{{name: "deploy"}}, force: true}},
+	}
+	for _, tt := range tests {
+		got, err := parseArgs(tt.args)
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%q: got %s, want %s", tt.args, describeOptions(got), describeOptions(&tt.want))
+		}
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	withTasks(t)
+	Task("build").Param("count", 1)
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--unknown"}, "unknown flag: --unknown"},
+		{[]string{"-x"}, "unknown flag: -x"},
+		{[]string{"build", "-count", "2"}, "unknown flag: -count"},
+		{[]string{"test", "--count", "2"}, "unknown flag: --count"},
+		{[]string{"-j"}, "flag -j requires a value"},
+		{[]string{"-j0"}, `invalid value "0" for flag -j`},
+		{[]string{"--jobs", "many"}, `invalid value "many" for flag --jobs`},
+		{[]string{"--verbose=yes"}, "flag --verbose does not take a value"},
+		{[]string{"count=2"}, "parameter count=2 given before any task"},
+		{[]string{"build", "other=2"}, `task "build" has no parameter "other"`},
+		{[]string{"build", "count=two"}, "two"},
+		{[]string{"build", "--count"}, "parameter --count requires a value"},
+		{[]string{"--json"}, "flag --json requires --list"},
+		{[]string{"-v", "-q"}, "flags --verbose and --quiet cannot be used together"},
+	}
+	for _, tt := range tests {
+		_, err := parseArgs(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got error %v, want %q", tt.args, err, tt.err)
+		}
+	}
+}
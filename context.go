@@ -1,53 +1,115 @@
 package gobl
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/kettek/gobl/pkg/steps"
 )
 
+// StepContext is the interface through which steps, and functions such as those passed to If, access a task's Context.
+type StepContext = steps.Context
+
 // Context provides a task-specific set of properties.
 type Context struct {
 	env                      []string
-	processKillChannels      []chan steps.Result
+	envSet                   bool
+	vars                     map[string]string
 	workingDirectory         string
 	originalWorkingDirectory string
 }
 
-// AddProcessKillChannel adds a provided channel to be sent a killed signal.
-func (c *Context) AddProcessKillChannel(r chan steps.Result) {
-	c.processKillChannels = append(c.processKillChannels, r)
+// GetEnv returns the current environment variables. Until the context is first reset, this is the process's environment.
+func (c *Context) GetEnv() []string {
+	if !c.envSet {
+		return os.Environ()
+	}
+	return append([]string(nil), c.env...)
 }
 
-// RemoveProcessKillChannel removes a provided channel from the process kill slice.
-func (c *Context) RemoveProcessKillChannel(r chan steps.Result) {
-	for i, v := range c.processKillChannels {
-		if v == r {
-			c.processKillChannels[i] = c.processKillChannels[len(c.processKillChannels)-1]
-			c.processKillChannels = c.processKillChannels[:len(c.processKillChannels)-1]
+// AddEnv adds environment variables in the form "KEY=value", replacing any existing variables with the same keys.
+func (c *Context) AddEnv(args ...string) {
+	c.ensureEnv()
+	for _, arg := range args {
+		key := arg
+		if i := strings.Index(arg, "="); i != -1 {
+			key = arg[:i]
 		}
+		c.env = append(removeEnv(c.env, func(k string) bool { return envKeyEqual(k, key) }), arg)
 	}
 }
 
-// GetProcessKillChannels returns the underlying process kill channels slice.
-func (c *Context) GetProcessKillChannels() []chan steps.Result {
-	return c.processKillChannels
+// UnsetEnv removes the named environment variables.
+func (c *Context) UnsetEnv(names ...string) {
+	c.ensureEnv()
+	c.env = removeEnv(c.env, func(k string) bool {
+		for _, name := range names {
+			if envKeyEqual(k, name) {
+				return true
+			}
+		}
+		return false
+	})
 }
 
-// GetEnv returns the current environment variables, including OS.
-func (c *Context) GetEnv() []string {
-	return append(os.Environ(), c.env...)
+// ClearEnv removes all environment variables except for the named ones.
+func (c *Context) ClearEnv(keep ...string) {
+	c.ensureEnv()
+	c.env = removeEnv(c.env, func(k string) bool {
+		for _, name := range keep {
+			if envKeyEqual(k, name) {
+				return false
+			}
+		}
+		return true
+	})
 }
 
-// AddEnv adds an environment variable.
-func (c *Context) AddEnv(args ...string) {
-	c.env = append(c.env, args...)
+// Reset prepares the context for a new run of its task. Its environment is replaced with env, or the process's environment if env is nil, and its variables are cleared.
+func (c *Context) Reset(env []string) {
+	if env == nil {
+		env = os.Environ()
+	}
+	c.env = append([]string(nil), env...)
+	c.envSet = true
+	c.vars = nil
+}
+
+func (c *Context) ensureEnv() {
+	if !c.envSet {
+		c.Reset(nil)
+	}
+}
+
+// removeEnv returns env without the variables whose keys match.
+func removeEnv(env []string, match func(string) bool) []string {
+	kept := env[:0]
+	for _, e := range env {
+		key := e
+		if i := strings.Index(e, "="); i != -1 {
+			key = e[:i]
+		}
+		if !match(key) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
 }
 
-// RunTask runs a task.
-func (c *Context) RunTask(n string) chan steps.Result {
-	return RunTask(n)
+// envKeyEqual returns if two environment variable keys are the same. Keys are case-insensitive on windows.
+func envKeyEqual(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// RunTask runs a task, cancelling it when the provided context is cancelled.
+func (c *Context) RunTask(ctx context.Context, n string) chan steps.Result {
+	return RunTaskContext(ctx, n)
 }
 
 // WorkingDirectory returns the context's working directory.
@@ -65,3 +127,28 @@ func (c *Context) SetWorkingDirectory(wd string) {
 func (c *Context) UpdateWorkingDirectory(wd string) {
 	c.workingDirectory = wd
 }
+
+// Var returns the value of the named variable, if set.
+func (c *Context) Var(name string) (string, bool) {
+	v, ok := c.vars[name]
+	return v, ok
+}
+
+// SetVar sets the named variable, which can be referenced in step arguments.
+func (c *Context) SetVar(name, value string) {
+	if c.vars == nil {
+		c.vars = make(map[string]string)
+	}
+	c.vars[name] = value
+}
+
+// Clone returns a copy of the context, so that changes to the copy's environment, variables, and working directory do not affect the original.
+func (c *Context) Clone() steps.Context {
+	clone := *c
+	clone.env = append([]string(nil), c.env...)
+	clone.vars = make(map[string]string, len(c.vars))
+	for k, v := range c.vars {
+		clone.vars[k] = v
+	}
+	return &clone
+}
@@ -0,0 +1,6 @@
+package gobl
+
+import "github.com/kettek/gobl/pkg/steps"
+
+// ErrTimeout can be compared against with errors.Is in Catch to check if a step or task timed out.
+var ErrTimeout = steps.ErrTimeout
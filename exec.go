@@ -0,0 +1,14 @@
+package gobl
+
+import "github.com/kettek/gobl/pkg/steps"
+
+// ExecResult is the result passed to Result by a successful Exec step.
+type ExecResult = steps.ExecResult
+
+// ExecError is the error passed to Catch when an Exec step's command fails. Use errors.As to retrieve it and inspect the command's exit code and output.
+type ExecError = steps.ExecError
+
+// Cmd groups a command's arguments for use with Pipe.
+func Cmd(args ...interface{}) []interface{} {
+	return args
+}
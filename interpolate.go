@@ -0,0 +1,6 @@
+package gobl
+
+import "github.com/kettek/gobl/pkg/steps"
+
+// Raw wraps a string argument so that it is used as is, without interpolating variable references such as "${NAME}".
+type Raw = steps.Raw
@@ -1,8 +1,13 @@
 package gobl
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/kettek/gobl/pkg/colors"
@@ -17,25 +22,180 @@ func Task(name string) *task.Task {
 	return task.GetTask(name)
 }
 
-// PrintTasks prints the currently available tasks.
+// PrintTasks prints the currently available tasks along with their descriptions, grouped by category. Hidden tasks are omitted.
 func PrintTasks() {
-	fmt.Printf("%s%s%s\n", colors.Info, messages.AvailableTasks, colors.Clear)
+	printTasks(false)
+}
+
+// PrintAllTasks prints all tasks, including hidden ones, along with their descriptions, grouped by category.
+func PrintAllTasks() {
+	printTasks(true)
+}
+
+// ListTasks returns machine-readable descriptions of all tasks, including hidden ones.
+func ListTasks() []task.Info {
+	infos := []task.Info{}
 	for _, k := range task.Tasks {
-		fmt.Printf("\t%s\n", k.Name)
+		infos = append(infos, k.Info())
 	}
+	return infos
+}
+
+// WriteTasksJSON writes the descriptions returned by ListTasks as JSON.
+func WriteTasksJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ListTasks())
 }
 
-// Go runs a specified task or lists all tasks if no task is specified.
+func printTasks(all bool) {
+	var groups []string
+	grouped := make(map[string][]*task.Task)
+	width := 0
+	for _, k := range task.Tasks {
+		if k.IsHidden() && !all {
+			continue
+		}
+		if _, ok := grouped[k.GroupName()]; !ok && k.GroupName() != "" {
+			groups = append(groups, k.GroupName())
+		}
+		grouped[k.GroupName()] = append(grouped[k.GroupName()], k)
+		if len(k.Name) > width {
+			width = len(k.Name)
+		}
+	}
+
+	fmt.Printf("%s%s%s\n", colors.Info, messages.AvailableTasks, colors.Clear)
+	printTaskGroup(grouped[""], width)
+	for _, group := range groups {
+		fmt.Printf(messages.TaskGroup+"\n", colors.Notice, group, colors.Clear)
+		printTaskGroup(grouped[group], width)
+	}
+}
+
+func printTaskGroup(tasks []*task.Task, width int) {
+	for _, k := range tasks {
+		if k.Description() == "" {
+			fmt.Printf("\t%s\n", k.Name)
+		} else {
+			fmt.Printf("\t%-*s  %s\n", width, k.Name, k.Description())
+		}
+	}
+}
+
+// Go parses the command-line arguments and runs each specified task in sequence, or lists all tasks if no task is specified. Run with "--help" to see the available flags.
 func Go() {
-	if len(os.Args) < 2 {
-		PrintTasks()
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n%s", err, usage)
+		os.Exit(2)
+	}
+	if opts.noColor {
+		colors.Disable()
+	}
+	messages.Verbose = opts.verbose
+	messages.Quiet = opts.quiet
+	task.Force = opts.force
+	task.MaxJobs = opts.jobs
+
+	if err := task.Validate(); err != nil {
+		fmt.Printf("%s%s%s\n", colors.Error, err, colors.Clear)
+		os.Exit(1)
+	}
+
+	if opts.help {
+		if len(opts.tasks) == 0 {
+			fmt.Print(usage + "\n")
+			printTasks(opts.all)
+			return
+		}
+		for _, t := range opts.tasks {
+			if err := PrintTaskHelp(t.name); err != nil {
+				fmt.Printf("%s%s%s\n", colors.Error, err, colors.Clear)
+				os.Exit(1)
+			}
+		}
 		return
 	}
-	<-RunTask(os.Args[1])
+
+	if opts.json {
+		if err := WriteTasksJSON(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if opts.list || len(opts.tasks) == 0 {
+		printTasks(opts.all)
+		return
+	}
+
+	if opts.dryRun {
+		for _, t := range opts.tasks {
+			if err := t.apply(); err != nil {
+				fmt.Printf("%s%s%s\n", colors.Error, err, colors.Clear)
+				os.Exit(2)
+			}
+			if err := task.WritePlan(os.Stdout, t.name); err != nil {
+				fmt.Printf("%s%s%s\n", colors.Error, err, colors.Clear)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	// Cancel running tasks on the first interrupt and exit immediately on the second.
+	report := &steps.StopReport{}
+	ctx, cancel := context.WithCancel(steps.WithStopReport(context.Background(), report))
+	defer cancel()
+	interrupts := make(chan os.Signal, 2)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		cancel()
+		<-interrupts
+		os.Exit(130)
+	}()
+
+	for _, t := range opts.tasks {
+		if err := t.apply(); err != nil {
+			fmt.Printf("%s%s%s\n", colors.Error, err, colors.Clear)
+			os.Exit(2)
+		}
+	}
+	// The tasks share a Scheduler so that dependencies they have in common run only once.
+	scheduler := task.NewScheduler(ctx, runTask)
+	for _, t := range opts.tasks {
+		if result := <-scheduler.Run(t.name); errors.Is(result.Error, context.Canceled) {
+			report.Print()
+			os.Exit(130)
+		} else if result.Error != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// PrintTaskHelp prints the details of the named task.
+func PrintTaskHelp(taskName string) error {
+	g := task.GetTask(taskName)
+	if g == nil {
+		return fmt.Errorf(messages.MissingTask, taskName)
+	}
+	g.WriteHelp(os.Stdout)
+	return nil
+}
+
+// RunTask begins running a specifc named task after running any of its dependencies.
+func RunTask(taskName string) chan steps.Result {
+	return RunTaskContext(context.Background(), taskName)
+}
+
+// RunTaskContext is like RunTask, but cancels the task and its dependencies when the provided context is cancelled.
+func RunTaskContext(ctx context.Context, taskName string) chan steps.Result {
+	return task.NewScheduler(ctx, runTask).Run(taskName)
 }
 
-// RunTask begins running a specifc named task.
-func RunTask(taskName string) (errChan chan steps.Result) {
+func runTask(ctx context.Context, taskName string) (errChan chan steps.Result) {
 	g := task.GetTask(taskName)
 	errChan = make(chan steps.Result)
 	if g == nil {
@@ -44,20 +204,22 @@ func RunTask(taskName string) (errChan chan steps.Result) {
 			errChan <- steps.Result{Result: nil, Error: fmt.Errorf(messages.MissingTask, taskName), Context: nil}
 		}()
 	} else {
-		fmt.Printf(messages.StartingTask+"\n", colors.Notice, colors.Clear, g.Name)
+		messages.Info(messages.StartingTask, colors.Notice, colors.Clear, g.Name)
 		t1 := time.Now()
 		go func() {
-			result := <-g.Execute()
+			result := <-g.Execute(ctx)
 			diff := time.Now().Sub(t1)
 
 			if result.Result != nil {
-				fmt.Printf("\t%s%v%s\n", colors.Info, result.Result, colors.Clear)
+				messages.Info("\t%s%v%s", colors.Info, result.Result, colors.Clear)
 			}
 
-			if result.Error != nil {
+			if errors.Is(result.Error, context.Canceled) {
+				fmt.Printf(messages.CancelledTask+"\n", colors.Warn, g.Name, colors.Clear)
+			} else if result.Error != nil {
 				fmt.Printf(messages.FailedTask+"\n", colors.Error, g.Name, colors.Clear, result.Error)
 			} else {
-				fmt.Printf(messages.CompletedTask+"\n", colors.Success, g.Name, diff, colors.Clear)
+				messages.Info(messages.CompletedTask, colors.Success, g.Name, diff, colors.Clear)
 			}
 			errChan <- result
 		}()
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of entries held in a Store.
+const (
+	ActionKind = "ac"
+	BlobKind   = "cas"
+)
+
+// ErrNotFound is returned by a Store when it does not contain the requested entry.
+var ErrNotFound = errors.New("not found in cache")
+
+// Store is the interface that cache backends adhere to. Actions map a key to an Entry and blobs map a content hash to file contents.
+type Store interface {
+	Get(kind, key string) ([]byte, error)
+	Put(kind, key string, data []byte) error
+}
+
+// Entry describes the outputs stored for a key.
+type Entry struct {
+	Outputs []Output `json:"outputs"`
+}
+
+// Output is a single file stored in the cache.
+type Output struct {
+	Path string      `json:"path"`
+	Hash string      `json:"hash"`
+	Mode os.FileMode `json:"mode"`
+}
+
+// Cache stores and restores files by key. Entries are always stored in Local and, if set, shared through Remote.
+type Cache struct {
+	Local  Store
+	Remote Store
+}
+
+// New returns a Cache that stores its entries in the given directory.
+func New(dir string) *Cache {
+	return &Cache{
+		Local: &Local{Dir: dir},
+	}
+}
+
+// Save stores the provided files under the given key.
+func (c *Cache) Save(key string, files []string) error {
+	var entry Entry
+	var blobs []blob
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		hash := Hash(data)
+		if err := c.Local.Put(BlobKind, hash, data); err != nil {
+			return err
+		}
+		blobs = append(blobs, blob{hash, data})
+		entry.Outputs = append(entry.Outputs, Output{
+			Path: filepath.ToSlash(file),
+			Hash: hash,
+			Mode: info.Mode().Perm(),
+		})
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := c.Local.Put(ActionKind, key, b); err != nil {
+		return err
+	}
+
+	// Upload the blobs before the action so that the remote never refers to missing blobs.
+	if c.Remote != nil {
+		for _, blob := range blobs {
+			if err := c.Remote.Put(BlobKind, blob.hash, blob.data); err != nil {
+				return err
+			}
+		}
+		return c.Remote.Put(ActionKind, key, b)
+	}
+	return nil
+}
+
+type blob struct {
+	hash string
+	data []byte
+}
+
+// get returns the entry from Local, falling back to Remote. Entries found in Remote are stored in Local.
+func (c *Cache) get(kind, key string) ([]byte, error) {
+	data, err := c.Local.Get(kind, key)
+	if err != ErrNotFound || c.Remote == nil {
+		return data, err
+	}
+	if data, err = c.Remote.Get(kind, key); err != nil {
+		return nil, err
+	}
+	if kind == BlobKind && Hash(data) != key {
+		return nil, fmt.Errorf("remote blob %s does not match its hash", key)
+	}
+	if err := c.Local.Put(kind, key, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Restore writes the files stored under the given key, returning false if the key is not in the cache. Entries with outputs outside of the working directory are treated as not being in the cache.
+func (c *Cache) Restore(key string) (bool, error) {
+	b, err := c.get(ActionKind, key)
+	if err == ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return false, err
+	}
+	// Entries may come from a remote that cannot be trusted, so outputs are only restored within the working directory.
+	for _, output := range entry.Outputs {
+		if !isLocalPath(output.Path) {
+			return false, nil
+		}
+	}
+	for _, output := range entry.Outputs {
+		data, err := c.get(BlobKind, output.Hash)
+		if err == ErrNotFound {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if Hash(data) != output.Hash {
+			return false, fmt.Errorf("cached %s does not match its hash", output.Path)
+		}
+		path := filepath.FromSlash(output.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(path, data, output.Mode); err != nil {
+			return false, err
+		}
+		if err := os.Chmod(path, output.Mode); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// isLocalPath reports whether the slash-separated path is relative and does not lead outside of the directory it is relative to.
+func isLocalPath(p string) bool {
+	p = filepath.Clean(filepath.FromSlash(p))
+	if p == "." || filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return false
+	}
+	return p != ".." && !strings.HasPrefix(p, ".."+string(filepath.Separator))
+}
+
+// Hash returns the hex-encoded sha256 of the given data.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
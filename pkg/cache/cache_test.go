@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// server is an in-memory HTTP cache that follows the protocol used by Remote.
+type server struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func newServer(t *testing.T) (*server, *httptest.Server) {
+	s := &server{entries: make(map[string][]byte)}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := s.entries[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.entries[r.URL.Path] = data
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) put(kind, key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries["/"+kind+"/"+key] = data
+}
+
+// chdir changes to a new temporary directory for the duration of the test, as the cache saves and restores paths relative to the working directory.
+func chdir(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// newCache returns a Cache with an empty local store that uses the given remote URL.
+func newCache(t *testing.T, url string) *Cache {
+	c := New(t.TempDir())
+	c.Remote = NewRemote(url)
+	return c
+}
+
+func writeFile(t *testing.T, path, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteHit(t *testing.T) {
+	chdir(t)
+	_, ts := newServer(t)
+	writeFile(t, filepath.Join("bin", "app"), "binary")
+
+	if err := newCache(t, ts.URL).Save("key", []string{filepath.Join("bin", "app")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll("bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cache on another machine has nothing stored locally.
+	hit, err := newCache(t, ts.URL).Restore("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hit {
+		t.Fatal("expected a hit")
+	}
+	data, err := os.ReadFile(filepath.Join("bin", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "binary" {
+		t.Errorf("restored %q, want %q", data, "binary")
+	}
+}
+
+func TestRemoteMiss(t *testing.T) {
+	chdir(t)
+	_, ts := newServer(t)
+
+	hit, err := newCache(t, ts.URL).Restore("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hit {
+		t.Error("expected a miss")
+	}
+}
+
+func TestRemoteServerError(t *testing.T) {
+	chdir(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	hit, err := newCache(t, ts.URL).Restore("key")
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected a 500 error, got %v", err)
+	}
+	if hit {
+		t.Error("expected a miss")
+	}
+	writeFile(t, "out", "data")
+	if err := newCache(t, ts.URL).Save("key", []string{"out"}); err == nil {
+		t.Error("expected Save to fail")
+	}
+}
+
+func TestRemoteUnreachable(t *testing.T) {
+	chdir(t)
+	_, ts := newServer(t)
+	url := ts.URL
+	ts.Close()
+
+	// The task falls back to building its outputs.
+	c := newCache(t, url)
+	hit, err := c.Restore("key")
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if hit {
+		t.Error("expected a miss")
+	}
+
+	// Entries stored locally are restored without the remote.
+	writeFile(t, "out", "data")
+	if err := c.Local.Put(BlobKind, Hash([]byte("data")), []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(Entry{Outputs: []Output{{Path: "out", Hash: Hash([]byte("data")), Mode: 0644}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Local.Put(ActionKind, "key", b); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove("out"); err != nil {
+		t.Fatal(err)
+	}
+	if hit, err := c.Restore("key"); err != nil || !hit {
+		t.Errorf("expected a local hit, got %v, %v", hit, err)
+	}
+}
+
+func TestRestoreRejectsTraversal(t *testing.T) {
+	dir := chdir(t)
+	s, ts := newServer(t)
+	if err := os.Mkdir("work", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("work"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("evil")
+	s.put(BlobKind, Hash(data), data)
+	for _, path := range []string{"../evil", "a/../../evil", filepath.ToSlash(filepath.Join(dir, "evil"))} {
+		b, err := json.Marshal(Entry{Outputs: []Output{{Path: path, Hash: Hash(data), Mode: 0644}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.put(ActionKind, "key", b)
+
+		hit, err := newCache(t, ts.URL).Restore("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hit {
+			t.Errorf("%s: expected a miss", path)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+			t.Fatalf("%s: file was written outside of the working directory", path)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Local is a Store that keeps its entries in a directory.
+type Local struct {
+	Dir string
+}
+
+// Get returns the entry of the given kind and key.
+func (l *Local) Get(kind, key string) ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(l.Dir, kind, key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// Put stores the entry of the given kind and key. Entries are written to a temporary file first so that partially written entries are never read.
+func (l *Local) Put(kind, key string, data []byte) error {
+	dir := filepath.Join(l.Dir, kind)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, key))
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Remote is a Store that keeps its entries on an HTTP server. Entries are retrieved with GET and stored with PUT requests to "<URL>/<kind>/<key>", the same layout used by Bazel's HTTP cache.
+type Remote struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewRemote returns a Remote for the given base URL.
+func NewRemote(url string) *Remote {
+	return &Remote{
+		URL: strings.TrimSuffix(url, "/"),
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Get returns the entry of the given kind and key.
+func (r *Remote) Get(kind, key string) ([]byte, error) {
+	resp, err := r.Client.Get(r.entryURL(kind, key))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", r.entryURL(kind, key), resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Put stores the entry of the given kind and key.
+func (r *Remote) Put(kind, key string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, r.entryURL(kind, key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("PUT %s: %s", r.entryURL(kind, key), resp.Status)
+	}
+	return nil
+}
+
+func (r *Remote) entryURL(kind, key string) string {
+	return r.URL + "/" + kind + "/" + key
+}
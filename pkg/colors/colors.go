@@ -21,3 +21,9 @@ var (
 	White   = "\033[1;37m"
 	Clear   = "\033[0m"
 )
+
+// Disable removes all color escape codes.
+func Disable() {
+	Info, Notice, Warn, Error, Success = "", "", "", "", ""
+	Black, Red, Green, Yellow, Purple, Magenta, Teal, White, Clear = "", "", "", "", "", "", "", "", ""
+}
@@ -0,0 +1,267 @@
+// Package dotenv parses environment files in the dotenv format used by tools such as docker-compose.
+//
+// Each line holds a KEY=value pair, optionally preceded by "export". Blank lines and lines starting with "#" are ignored. Unquoted values are trimmed and end at a " #" comment. Single-quoted values are literal. Double-quoted values support the escapes \n, \r, \t, \", \\ and \$. Both kinds of quoted values may span multiple lines. Unquoted and double-quoted values expand $NAME, ${NAME}, ${NAME:-default} and ${NAME-default} references, where defaults may contain references themselves.
+package dotenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Var is a variable set by an environment file.
+type Var struct {
+	Key   string
+	Value string
+}
+
+// Parse parses the contents of an environment file, returning its variables in order. References to variables are resolved from variables set earlier in the file, then with lookup, which may be nil. References to undefined variables expand to an empty string.
+func Parse(src string, lookup func(string) (string, bool)) ([]Var, error) {
+	p := &parser{src: strings.ReplaceAll(src, "\r\n", "\n"), line: 1, lookup: lookup, vars: make(map[string]string)}
+	var vars []Var
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			return vars, nil
+		}
+		if p.src[p.pos] == '#' {
+			p.skipLine()
+			continue
+		}
+		v, err := p.variable()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+		p.vars[v.Key] = v.Value
+		vars = append(vars, v)
+	}
+}
+
+type parser struct {
+	src    string
+	pos    int
+	line   int
+	lookup func(string) (string, bool)
+	vars   map[string]string
+}
+
+// skipBlank skips whitespace, including newlines.
+func (p *parser) skipBlank() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n", p.src[p.pos]) != -1 {
+		if p.src[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+// skipSpace skips spaces and tabs.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine skips to the start of the next line.
+func (p *parser) skipLine() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+func (p *parser) variable() (Var, error) {
+	key := p.key()
+	if key == "export" {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] != '=' {
+			key = p.key()
+		}
+	}
+	if !isName(key) {
+		return Var{}, fmt.Errorf("invalid variable name %q", key)
+	}
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return Var{}, fmt.Errorf("expected \"=\" after %s", key)
+	}
+	p.pos++
+	p.skipSpace()
+
+	var value string
+	var err error
+	if p.pos < len(p.src) && (p.src[p.pos] == '\'' || p.src[p.pos] == '"') {
+		value, err = p.quoted(p.src[p.pos])
+		if err != nil {
+			return Var{}, err
+		}
+		// Allow a comment after the closing quote.
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '#' {
+			return Var{}, fmt.Errorf("unexpected %q after quoted value of %s", p.src[p.pos], key)
+		}
+		p.skipLine()
+	} else {
+		start := p.pos
+		p.skipLine()
+		raw := p.src[start:p.pos]
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '#' && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+				raw = raw[:i]
+				break
+			}
+		}
+		value = p.expand(strings.TrimSpace(raw))
+	}
+	return Var{Key: key, Value: value}, nil
+}
+
+// key reads a key, which ends at whitespace or "=".
+func (p *parser) key() string {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n=", p.src[p.pos]) == -1 {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// quoted reads a quoted value, starting at its opening quote.
+func (p *parser) quoted(quote byte) (string, error) {
+	startLine := p.line
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			p.line = startLine
+			return "", fmt.Errorf("unterminated %c quote", quote)
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\n':
+			p.line++
+			b.WriteByte(c)
+		case quote == '"' && c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			switch e := p.src[p.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(e)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		case quote == '"' && c == '$':
+			v, n := p.reference(p.src[p.pos:])
+			b.WriteString(v)
+			p.pos += n
+			continue
+		default:
+			b.WriteByte(c)
+		}
+		p.pos++
+	}
+}
+
+// expand expands the variable references in s.
+func (p *parser) expand(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		v, n := p.reference(s[i:])
+		b.WriteString(v)
+		i += n
+	}
+	return b.String()
+}
+
+// reference expands the variable reference at the start of s, which begins with "$", returning its value and length. A "$" that does not start a reference is returned as is.
+func (p *parser) reference(s string) (string, int) {
+	if len(s) > 1 && s[1] == '{' {
+		end := closingBrace(s)
+		if end == -1 {
+			return "$", 1
+		}
+		name, def, hasDefault := s[2:end], "", false
+		emptyIsUnset := false
+		if i := strings.Index(name, ":-"); i != -1 {
+			name, def, hasDefault, emptyIsUnset = name[:i], name[i+2:], true, true
+		} else if i := strings.IndexByte(name, '-'); i != -1 {
+			name, def, hasDefault = name[:i], name[i+1:], true
+		}
+		if !isName(name) {
+			return "$", 1
+		}
+		v, ok := p.get(name)
+		if hasDefault && (!ok || (emptyIsUnset && v == "")) {
+			v = p.expand(def)
+		}
+		return v, end + 1
+	}
+	n := 1
+	for n < len(s) && (s[n] == '_' || isLetter(s[n]) || (n > 1 && isDigit(s[n]))) {
+		n++
+	}
+	if n == 1 {
+		return "$", 1
+	}
+	v, _ := p.get(s[1:n])
+	return v, n
+}
+
+// closingBrace returns the index of the "}" that closes the "${" at the start of s, skipping any references nested in a default value, or -1 if there is none.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '{' && s[i-1] == '$':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// get returns the value of a variable set earlier in the file or found with lookup.
+func (p *parser) get(name string) (string, bool) {
+	if v, ok := p.vars[name]; ok {
+		return v, true
+	}
+	if p.lookup != nil {
+		return p.lookup(name)
+	}
+	return "", false
+}
+
+func isName(s string) bool {
+	if s == "" || isDigit(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' && !isLetter(s[i]) && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,91 @@
+package dotenv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	env := map[string]string{"HOME": "/home/gobl", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	tests := []struct {
+		name, src string
+		want      []Var
+	}{
+		{"plain", "A=1\nB = two words \n", []Var{{"A", "1"}, {"B", "two words"}}},
+		{"export", "export A=1\nexport=2\n", []Var{{"A", "1"}, {"export", "2"}}},
+		{"blank and comments", "\n# comment\n  \nA=1 # trailing\nB=a#b\n\t# indented\n", []Var{{"A", "1"}, {"B", "a#b"}}},
+		{"empty", "A=\nB=''\n", []Var{{"A", ""}, {"B", ""}}},
+		{"crlf", "A=1\r\nB=2\r\n", []Var{{"A", "1"}, {"B", "2"}}},
+		{"single quotes", `A='$HOME \n # not a comment'`, []Var{{"A", `$HOME \n # not a comment`}}},
+		{"double quotes", `A="a\nb\tc \"d\" \\ \$HOME \q" # comment`, []Var{{"A", "a\nb\tc \"d\" \\ $HOME \\q"}}},
+		{"multi-line", "A=\"one\ntwo\"\nB='three\nfour'\nC=5", []Var{{"A", "one\ntwo"}, {"B", "three\nfour"}, {"C", "5"}}},
+		{"references", "A=$HOME/bin\nB=${A}:x\nC=\"${B}\"\nD='${A}'\nE=$UNSET.", []Var{{"A", "/home/gobl/bin"}, {"B", "/home/gobl/bin:x"}, {"C", "/home/gobl/bin:x"}, {"D", "${A}"}, {"E", "."}}},
+		{"file overrides lookup", "HOME=/root\nA=$HOME", []Var{{"HOME", "/root"}, {"A", "/root"}}},
+		{"defaults", "A=${UNSET:-def}\nB=${EMPTY:-def}\nC=${EMPTY-def}\nD=${HOME:-def}\nE=${UNSET-def}", []Var{{"A", "def"}, {"B", "def"}, {"C", ""}, {"D", "/home/gobl"}, {"E", "def"}}},
+		{"nested defaults", "A=${UNSET:-${HOME}}/x\nB=\"${UNSET:-${OTHER:-deep}}\"\nC=${HOME:-${UNSET}}", []Var{{"A", "/home/gobl/x"}, {"B", "deep"}, {"C", "/home/gobl"}}},
+		{"not references", "A=$\nB=${\nC=$1\nD=${1A}", []Var{{"A", "$"}, {"B", "${"}, {"C", "$1"}, {"D", "${1A}"}}},
+	}
+	for _, tt := range tests {
+		vars, err := Parse(tt.src, lookup)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(vars) != len(tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, vars, tt.want)
+			continue
+		}
+		for i := range vars {
+			if vars[i] != tt.want[i] {
+				t.Errorf("%s: got %q, want %q", tt.name, vars[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestParseLayers(t *testing.T) {
+	base, err := Parse("A=1\nB=2\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := make(map[string]string)
+	for _, v := range base {
+		env[v.Key] = v.Value
+	}
+	// A later file sees the variables of earlier ones through lookup.
+	local, err := Parse("B=3\nC=${A}${B}\n", func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Var{{"B", "3"}, {"C", "13"}}
+	for i := range want {
+		if local[i] != want[i] {
+			t.Errorf("got %q, want %q", local[i], want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{"1A=x", `line 1: invalid variable name "1A"`},
+		{"A=1\nB", `line 2: expected "=" after B`},
+		{"A-B=1", `line 1: invalid variable name "A-B"`},
+		{"A=1\nB=\"open\nstill open", "line 2: unterminated \" quote"},
+		{"A='x' y", `line 1: unexpected 'y' after quoted value of A`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got error %v, want %q", tt.src, err, tt.err)
+		}
+	}
+}
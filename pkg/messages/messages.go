@@ -2,11 +2,30 @@ package messages
 
 // Our messages.
 var (
-	AvailableTasks = "✨  Available Tasks"
-	ExistingTask   = "⚠️  task \"%s\" is defined multiple times, using last instance"
-	MissingTask    = "🛑  task \"%s\" does not exist"
-	StartingTask   = "⚡  %sStarting Task%s \"%s\""
-	CompletedTask  = "✔️  %sTask \"%s\" Complete in %s%s"
-	FailedTask     = "❌  %sTask \"%s\" Failed%s: %s"
-	WatchingTask   = "👀  %sWatching%s"
+	AvailableTasks   = "✨  Available Tasks"
+	TaskGroup        = "📂  %s%s%s"
+	ExistingTask     = "⚠️  task \"%s\" is defined multiple times, using last instance"
+	MissingTask      = "🛑  task \"%s\" does not exist"
+	StartingTask     = "⚡  %sStarting Task%s \"%s\""
+	CompletedTask    = "✔️  %sTask \"%s\" Complete in %s%s"
+	FailedTask       = "❌  %sTask \"%s\" Failed%s: %s"
+	CancelledTask    = "🚫  %sTask \"%s\" Cancelled%s"
+	RestartingTask   = "🔄  %sRestarting Task%s \"%s\""
+	StoppedProcesses = "🛑  %sStopped Processes%s"
+	ExitedProcess    = "\t✔️  %s%s (pid %d) exited cleanly%s within %[6]s of signal \"%[5]v\""
+	KilledProcess    = "\t💀  %s%s (pid %d) was killed%s after not exiting within %[6]s of signal \"%[5]v\""
+	RetryingStep     = "♻️  %sAttempt %d/%d of \"%v\" failed%s: %s, retrying in %s"
+	WatchingTask     = "👀  %sWatching%s"
+	UpToDateTask     = "💤  %sTask \"%s\" is up to date%s"
+	PlannedTask      = "📝  %sWould Run Task%s \"%s\""
+	CacheHit         = "📦  %sRestored \"%s\" outputs from cache%s"
+	CacheMiss        = "📭  %sNo cached outputs for \"%s\"%s"
+	CacheError       = "⚠️  cache error for \"%s\": %s"
+
+	MatrixCombination = "🔀  %sRunning Combination%s %s"
+	MatrixSummary     = "🧮  %sMatrix Summary for \"%s\"%s"
+
+	DependencyCycle   = "🔁  dependency cycle: %s"
+	MissingDependency = "🛑  task \"%s\" depends on missing task \"%s\""
+	FailedDependency  = "dependency \"%s\" failed: %s"
 )
@@ -0,0 +1,25 @@
+package messages
+
+import "fmt"
+
+// Output levels.
+var (
+	Quiet   bool
+	Verbose bool
+)
+
+// Info prints a formatted message followed by a newline unless Quiet is set.
+func Info(format string, a ...interface{}) {
+	if Quiet {
+		return
+	}
+	fmt.Printf(format+"\n", a...)
+}
+
+// Debug prints a formatted message followed by a newline only if Verbose is set.
+func Debug(format string, a ...interface{}) {
+	if !Verbose || Quiet {
+		return
+	}
+	fmt.Printf(format+"\n", a...)
+}
@@ -0,0 +1,466 @@
+// Package shell parses a small subset of POSIX shell syntax so that shell one-liners can be run without depending on a system shell.
+//
+// Supported are simple commands with single and double quoting, backslash escapes, $NAME and ${NAME} expansion, NAME=value assignments, pipelines joined with "|", lists joined with "&&", "||", ";" or newlines, the redirects "<", ">", ">>", "2>", "2>>", "2>&1", "&>" and "&>>", and comments. Command substitution, subshells, background jobs, and control flow are not supported.
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Script is a list of pipelines.
+type Script struct {
+	Items []*Item
+}
+
+// Item is a pipeline in a Script along with the operator that precedes it. The first Item's Op is empty.
+type Item struct {
+	Op       string // "", ";", "&&" or "||".
+	Pipeline *Pipeline
+}
+
+// Pipeline is one or more commands whose output is connected to the following command's input.
+type Pipeline struct {
+	Commands []*Command
+}
+
+// Command is a simple command.
+type Command struct {
+	Assigns   []*Assign
+	Args      []Word
+	Redirects []*Redirect
+}
+
+// Assign is a NAME=value assignment.
+type Assign struct {
+	Name  string
+	Value Word
+}
+
+// Redirect redirects one of a command's file descriptors.
+type Redirect struct {
+	Fd     int    // The redirected file descriptor, or -1 for both stdout and stderr.
+	Op     string // "<", ">", ">>" or ">&".
+	Target Word
+}
+
+// Word is a shell word made up of literal and variable parts.
+type Word []Part
+
+// Part is part of a Word.
+type Part struct {
+	Text   string // The literal text, or the variable name if Var is set.
+	Var    bool   // Whether the part is a variable to be expanded.
+	Quoted bool   // Whether the part was quoted, in which case it is not subject to globbing.
+}
+
+// Parse parses a script.
+func Parse(src string) (*Script, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.script()
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenOp
+	tokenRedirect
+)
+
+type token struct {
+	kind tokenKind
+	op   string
+	fd   int
+	word Word
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of input"
+	case tokenWord:
+		var s string
+		for _, p := range t.word {
+			if p.Var {
+				s += "$" + p.Text
+			} else {
+				s += p.Text
+			}
+		}
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%q", t.op)
+}
+
+type lexer struct {
+	src    []rune
+	pos    int
+	tokens []token
+}
+
+func lex(src string) ([]token, error) {
+	l := &lexer{src: []rune(src)}
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		l.tokens = append(l.tokens, t)
+		if t.kind == tokenEOF {
+			return l.tokens, nil
+		}
+	}
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\r' {
+			l.pos++
+		} else if c == '\\' && l.peek(1) == '\n' {
+			l.pos += 2
+		} else if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		} else {
+			break
+		}
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF}, nil
+	}
+	switch c := l.src[l.pos]; c {
+	case '\n', ';':
+		l.pos++
+		return token{kind: tokenOp, op: ";"}, nil
+	case '|':
+		if l.peek(1) == '|' {
+			l.pos += 2
+			return token{kind: tokenOp, op: "||"}, nil
+		}
+		l.pos++
+		return token{kind: tokenOp, op: "|"}, nil
+	case '&':
+		if l.peek(1) == '&' {
+			l.pos += 2
+			return token{kind: tokenOp, op: "&&"}, nil
+		}
+		if l.peek(1) == '>' {
+			l.pos += 2
+			if l.peek(0) == '>' {
+				l.pos++
+				return token{kind: tokenRedirect, op: ">>", fd: -1}, nil
+			}
+			return token{kind: tokenRedirect, op: ">", fd: -1}, nil
+		}
+		return token{}, fmt.Errorf("background commands are not supported")
+	case '<':
+		return l.redirect(0)
+	case '>':
+		return l.redirect(1)
+	case '(', ')':
+		return token{}, fmt.Errorf("subshells are not supported")
+	}
+	// A number immediately followed by a redirect is the file descriptor being redirected.
+	if end := l.pos; l.src[end] >= '0' && l.src[end] <= '9' {
+		for end < len(l.src) && l.src[end] >= '0' && l.src[end] <= '9' {
+			end++
+		}
+		if end < len(l.src) && (l.src[end] == '<' || l.src[end] == '>') {
+			var fd int
+			fmt.Sscan(string(l.src[l.pos:end]), &fd)
+			l.pos = end
+			return l.redirect(fd)
+		}
+	}
+	return l.word()
+}
+
+// redirect lexes a redirect operator at the current position for the given file descriptor.
+func (l *lexer) redirect(fd int) (token, error) {
+	if l.src[l.pos] == '<' {
+		if l.peek(1) == '<' {
+			return token{}, fmt.Errorf("here-documents are not supported")
+		}
+		l.pos++
+		return token{kind: tokenRedirect, op: "<", fd: fd}, nil
+	}
+	switch l.peek(1) {
+	case '>':
+		l.pos += 2
+		return token{kind: tokenRedirect, op: ">>", fd: fd}, nil
+	case '&':
+		l.pos += 2
+		return token{kind: tokenRedirect, op: ">&", fd: fd}, nil
+	}
+	l.pos++
+	return token{kind: tokenRedirect, op: ">", fd: fd}, nil
+}
+
+func (l *lexer) word() (token, error) {
+	var w Word
+	literal := func(s string, quoted bool) {
+		if n := len(w); n > 0 && !w[n-1].Var && w[n-1].Quoted == quoted {
+			w[n-1].Text += s
+			return
+		}
+		w = append(w, Part{Text: s, Quoted: quoted})
+	}
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case strings.ContainsRune(" \t\r\n;&|<>()", c):
+			return token{kind: tokenWord, word: w}, nil
+		case c == '\'':
+			end := l.pos + 1
+			for end < len(l.src) && l.src[end] != '\'' {
+				end++
+			}
+			if end >= len(l.src) {
+				return token{}, fmt.Errorf("unterminated single quote")
+			}
+			literal(string(l.src[l.pos+1:end]), true)
+			l.pos = end + 1
+		case c == '"':
+			l.pos++
+			// Ensure that "" produces an empty word.
+			literal("", true)
+			for {
+				if l.pos >= len(l.src) {
+					return token{}, fmt.Errorf("unterminated double quote")
+				}
+				c := l.src[l.pos]
+				if c == '"' {
+					l.pos++
+					break
+				}
+				switch {
+				case c == '\\' && strings.ContainsRune("$`\"\\\n", l.peek(1)):
+					if l.peek(1) != '\n' {
+						literal(string(l.peek(1)), true)
+					}
+					l.pos += 2
+				case c == '$':
+					p, err := l.variable(true)
+					if err != nil {
+						return token{}, err
+					}
+					if p.Var {
+						w = append(w, p)
+					} else {
+						literal(p.Text, true)
+					}
+				case c == '`':
+					return token{}, fmt.Errorf("command substitution is not supported")
+				default:
+					literal(string(c), true)
+					l.pos++
+				}
+			}
+		case c == '\\':
+			if l.pos+1 < len(l.src) {
+				literal(string(l.src[l.pos+1]), true)
+				l.pos += 2
+			} else {
+				literal("\\", false)
+				l.pos++
+			}
+		case c == '$':
+			p, err := l.variable(false)
+			if err != nil {
+				return token{}, err
+			}
+			if p.Var {
+				w = append(w, p)
+			} else {
+				literal(p.Text, false)
+			}
+		case c == '`':
+			return token{}, fmt.Errorf("command substitution is not supported")
+		default:
+			literal(string(c), false)
+			l.pos++
+		}
+	}
+	return token{kind: tokenWord, word: w}, nil
+}
+
+// variable lexes a variable reference at the current position. A lone "$" is returned as a literal part.
+func (l *lexer) variable(quoted bool) (Part, error) {
+	switch c := l.peek(1); {
+	case c == '{':
+		end := l.pos + 2
+		for end < len(l.src) && l.src[end] != '}' {
+			end++
+		}
+		if end >= len(l.src) {
+			return Part{}, fmt.Errorf("unterminated ${")
+		}
+		name := string(l.src[l.pos+2 : end])
+		if !IsName(name) && name != "?" {
+			return Part{}, fmt.Errorf("bad substitution ${%s}", name)
+		}
+		l.pos = end + 1
+		return Part{Text: name, Var: true, Quoted: quoted}, nil
+	case c == '?':
+		l.pos += 2
+		return Part{Text: "?", Var: true, Quoted: quoted}, nil
+	case c == '(':
+		return Part{}, fmt.Errorf("command substitution is not supported")
+	case c == '_' || isLetter(c):
+		end := l.pos + 1
+		for end < len(l.src) && (l.src[end] == '_' || isLetter(l.src[end]) || (l.src[end] >= '0' && l.src[end] <= '9')) {
+			end++
+		}
+		name := string(l.src[l.pos+1 : end])
+		l.pos = end
+		return Part{Text: name, Var: true, Quoted: quoted}, nil
+	}
+	l.pos++
+	return Part{Text: "$", Quoted: quoted}, nil
+}
+
+func isLetter(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// IsName returns if s is a valid variable name.
+func IsName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && !isLetter(c) && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) script() (*Script, error) {
+	s := &Script{}
+	op := ""
+	for {
+		// Skip empty statements, such as blank lines.
+		for (op == "" || op == ";") && p.peek().kind == tokenOp && p.peek().op == ";" {
+			p.next()
+		}
+		if t := p.peek(); t.kind == tokenEOF {
+			if op == "&&" || op == "||" {
+				return nil, fmt.Errorf("syntax error: expected a command after %q", op)
+			}
+			return s, nil
+		}
+		// Allow line breaks after && and ||.
+		for p.peek().kind == tokenOp && p.peek().op == ";" {
+			p.next()
+		}
+		pl, err := p.pipeline()
+		if err != nil {
+			return nil, err
+		}
+		s.Items = append(s.Items, &Item{Op: op, Pipeline: pl})
+		switch t := p.next(); {
+		case t.kind == tokenEOF:
+			return s, nil
+		case t.kind == tokenOp && (t.op == ";" || t.op == "&&" || t.op == "||"):
+			op = t.op
+		default:
+			return nil, fmt.Errorf("syntax error near %s", t)
+		}
+	}
+}
+
+func (p *parser) pipeline() (*Pipeline, error) {
+	pl := &Pipeline{}
+	for {
+		c, err := p.command()
+		if err != nil {
+			return nil, err
+		}
+		pl.Commands = append(pl.Commands, c)
+		if t := p.peek(); t.kind != tokenOp || t.op != "|" {
+			return pl, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) command() (*Command, error) {
+	c := &Command{}
+	for {
+		t := p.peek()
+		switch t.kind {
+		case tokenWord:
+			p.next()
+			if a := assignment(t.word); a != nil && len(c.Args) == 0 {
+				c.Assigns = append(c.Assigns, a)
+			} else {
+				c.Args = append(c.Args, t.word)
+			}
+			continue
+		case tokenRedirect:
+			p.next()
+			target := p.next()
+			if target.kind != tokenWord {
+				return nil, fmt.Errorf("syntax error: expected a file after %q, found %s", t.op, target)
+			}
+			c.Redirects = append(c.Redirects, &Redirect{Fd: t.fd, Op: t.op, Target: target.word})
+			continue
+		}
+		if len(c.Args) == 0 && len(c.Assigns) == 0 && len(c.Redirects) == 0 {
+			return nil, fmt.Errorf("syntax error: expected a command, found %s", t)
+		}
+		if len(c.Args) == 0 && len(c.Redirects) > 0 {
+			return nil, fmt.Errorf("syntax error: redirect without a command")
+		}
+		return c, nil
+	}
+}
+
+// assignment returns the word as an Assign if it is of the form NAME=value.
+func assignment(w Word) *Assign {
+	if len(w) == 0 || w[0].Var || w[0].Quoted {
+		return nil
+	}
+	i := strings.Index(w[0].Text, "=")
+	if i < 1 || !IsName(w[0].Text[:i]) {
+		return nil
+	}
+	value := Word{}
+	if rest := w[0].Text[i+1:]; rest != "" {
+		value = append(value, Part{Text: rest})
+	}
+	return &Assign{Name: w[0].Text[:i], Value: append(value, w[1:]...)}
+}
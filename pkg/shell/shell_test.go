@@ -0,0 +1,126 @@
+package shell
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// format returns a canonical form of a parsed script, in which quoted literals are single-quoted and variables are written as ${NAME}.
+func format(s *Script) string {
+	var items []string
+	for _, item := range s.Items {
+		var cmds []string
+		for _, c := range item.Pipeline.Commands {
+			var words []string
+			for _, a := range c.Assigns {
+				words = append(words, a.Name+"="+formatWord(a.Value))
+			}
+			for _, w := range c.Args {
+				words = append(words, formatWord(w))
+			}
+			for _, r := range c.Redirects {
+				fd := strconv.Itoa(r.Fd)
+				if r.Fd == -1 {
+					fd = "&"
+				}
+				words = append(words, fd+r.Op+formatWord(r.Target))
+			}
+			cmds = append(cmds, strings.Join(words, " "))
+		}
+		pl := strings.Join(cmds, " | ")
+		if item.Op != "" {
+			pl = item.Op + " " + pl
+		}
+		items = append(items, pl)
+	}
+	return strings.Join(items, " ")
+}
+
+func formatWord(w Word) string {
+	var s string
+	for _, p := range w {
+		switch {
+		case p.Var && p.Quoted:
+			s += `"${` + p.Text + `}"`
+		case p.Var:
+			s += "${" + p.Text + "}"
+		case p.Quoted:
+			s += "'" + p.Text + "'"
+		default:
+			s += p.Text
+		}
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"echo hello world", "echo hello world"},
+		{"  echo   a\tb  ", "echo a b"},
+		{"echo 'single $X \\n' \"double $X\"", `echo 'single $X \n' 'double '"${X}"`},
+		{`echo "a\"b\\c\$d\e"`, `echo 'a"b\c$d\e'`},
+		{`echo a\ b \$X \'`, `echo a' 'b '$'X '''`},
+		{`echo ""`, `echo ''`},
+		{"echo $X ${Y}z $? $ $1", "echo ${X} ${Y}z ${?} $ $1"},
+		{"echo a#b # comment", "echo a#b"},
+		{"echo a \\\n b", "echo a b"},
+		{"a && b || c; d\ne", "a && b || c ; d ; e"},
+		{"a &&\n b", "a && b"},
+		{"\n\na;;\n b\n", "a ; b"},
+		{"a | b | c && d", "a | b | c && d"},
+		{"cmd < in > out 2> err", "cmd 0<in 1>out 2>err"},
+		{"cmd >> out 2>> err", "cmd 1>>out 2>>err"},
+		{"cmd 2>&1", "cmd 2>&1"},
+		{"cmd &> all", "cmd &>all"},
+		{"cmd &>> all", "cmd &>>all"},
+		{"cmd >out", "cmd 1>out"},
+		{"echo 2 > out", "echo 2 1>out"},
+		{"A=1 B=$X cmd C=2", "A=1 B=${X} cmd C=2"},
+		{"A=1", "A=1"},
+		{"A= cmd", "A= cmd"},
+		{`A="a b" cmd`, "A='a b' cmd"},
+		{"'A=1' cmd", "'A=1' cmd"},
+		{"1A=1", "1A=1"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if got := format(s); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{"echo 'open", "unterminated single quote"},
+		{`echo "open`, "unterminated double quote"},
+		{"echo ${X", "unterminated ${"},
+		{"echo ${X-y}", "bad substitution ${X-y}"},
+		{"echo $(date)", "command substitution is not supported"},
+		{"echo `date`", "command substitution is not supported"},
+		{"sleep 1 &", "background commands are not supported"},
+		{"(cd dir)", "subshells are not supported"},
+		{"cat <<EOF", "here-documents are not supported"},
+		{"a &&", `syntax error: expected a command after "&&"`},
+		{"a || | b", `syntax error: expected a command, found "|"`},
+		{"| b", `syntax error: expected a command, found "|"`},
+		{"cmd >", `syntax error: expected a file after ">", found end of input`},
+		{"> out", "syntax error: redirect without a command"},
+		{"A=1 > out", "syntax error: redirect without a command"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: got error %v, want %q", tt.src, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+)
+
+// CaptureStep stores the result of another step in a variable, so that it can be referenced by later steps.
+type CaptureStep struct {
+	Step Step
+	Name string
+}
+
+// Run runs the wrapped step and, if it succeeds, sets the variable to its result as returned by ResultString.
+func (s CaptureStep) Run(ctx context.Context, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		res := <-s.Step.Run(ctx, r)
+		if res.Error == nil {
+			r.Context.SetVar(s.Name, ResultString(res.Result))
+		}
+		result <- res
+	}()
+	return result
+}
+
+// Unwrap returns the step being captured.
+func (s CaptureStep) Unwrap() Step {
+	return s.Step
+}
+
+// String returns the wrapped step's description along with the variable it sets.
+func (s CaptureStep) String() string {
+	return fmt.Sprintf("%v (as %s)", s.Step, s.Name)
+}
@@ -1,17 +1,25 @@
 package steps
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // CatchStep handles catching errors from any preceding steps.
 type CatchStep struct {
 	Func func(error) error
 }
 
-// Run runs the catch's function.
-func (s CatchStep) Run(r Result) chan Result {
+// Run runs the catch's function. The error passed to the function wraps the original error, so it can be inspected with errors.Is and errors.As.
+func (s CatchStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 	go func() {
-		result <- Result{nil, s.Func(fmt.Errorf("%v: %v", r.Error, r.Result)), nil}
+		result <- Result{nil, s.Func(fmt.Errorf("%w: %v", r.Error, r.Result)), nil}
 	}()
 	return result
 }
+
+// String returns "catch".
+func (s CatchStep) String() string {
+	return "catch"
+}
@@ -1,6 +1,7 @@
 package steps
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,12 +12,17 @@ type ChdirStep struct {
 	Path string
 }
 
-// Run changes the working directory.
-func (s ChdirStep) Run(pr Result) chan Result {
+// Run changes the working directory, interpolating any variable references in the path.
+func (s ChdirStep) Run(ctx context.Context, pr Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
-		wd := filepath.Join(pr.Context.WorkingDirectory(), s.Path)
+		path, err := Interpolate(s.Path, pr)
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		wd := filepath.Join(pr.Context.WorkingDirectory(), path)
 		info, err := os.Stat(wd)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -34,3 +40,8 @@ func (s ChdirStep) Run(pr Result) chan Result {
 
 	return result
 }
+
+// String returns the directory that the step changes to.
+func (s ChdirStep) String() string {
+	return "chdir " + s.Path
+}
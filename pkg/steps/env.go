@@ -1,16 +1,91 @@
 package steps
 
+import (
+	"context"
+	"strings"
+)
+
 // EnvStep sets up environment variables to use.
 type EnvStep struct {
 	Args []string
 }
 
-// Run adds the env var to the task.
-func (s EnvStep) Run(pr Result) chan Result {
+// Run adds the env var to the task, interpolating any variable references in the values.
+func (s EnvStep) Run(ctx context.Context, pr Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		args := make([]string, len(s.Args))
+		for i, arg := range s.Args {
+			v, err := Interpolate(arg, pr)
+			if err != nil {
+				result <- Result{nil, err, nil}
+				return
+			}
+			args[i] = v
+		}
+		pr.Context.AddEnv(args...)
+		result <- Result{}
+	}()
+	return result
+}
+
+// String returns the environment variables that the step sets.
+func (s EnvStep) String() string {
+	return "env " + strings.Join(s.Args, " ")
+}
+
+// UnenvStep removes environment variables.
+type UnenvStep struct {
+	Names []string
+}
+
+// Run removes the environment variables from the task.
+func (s UnenvStep) Run(ctx context.Context, pr Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		pr.Context.UnsetEnv(s.Names...)
+		result <- Result{}
+	}()
+	return result
+}
+
+// String returns the environment variables that the step removes.
+func (s UnenvStep) String() string {
+	return "unenv " + strings.Join(s.Names, " ")
+}
+
+// ClearEnvStep removes all environment variables except for those in an allowlist.
+type ClearEnvStep struct {
+	Keep []string
+}
+
+// Run removes the environment variables from the task.
+func (s ClearEnvStep) Run(ctx context.Context, pr Result) chan Result {
 	result := make(chan Result)
 	go func() {
-		pr.Context.AddEnv(s.Args...)
+		pr.Context.ClearEnv(s.Keep...)
 		result <- Result{}
 	}()
 	return result
 }
+
+// String returns the environment variables that the step keeps.
+func (s ClearEnvStep) String() string {
+	if len(s.Keep) == 0 {
+		return "clearenv"
+	}
+	return "clearenv except " + strings.Join(s.Keep, " ")
+}
+
+type envKey struct{}
+
+// WithEnv returns a context whose tasks start with the given environment. A nil env starts tasks with the process's environment.
+func WithEnv(ctx context.Context, env []string) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// EnvFrom returns the environment that tasks run with the context should start with, or nil if they should start with the process's environment.
+func EnvFrom(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return env
+}
@@ -0,0 +1,71 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kettek/gobl/pkg/dotenv"
+)
+
+// EnvFileStep sets environment variables from dotenv files.
+type EnvFileStep struct {
+	Paths    []string
+	Required bool // Whether a missing file is an error.
+}
+
+// Run reads each file in order, relative to the working directory, so that variables in later files take precedence. Variable references in the files can refer to variables set by earlier files or the task's environment. Missing files are skipped unless the step is Required.
+func (s EnvFileStep) Run(ctx context.Context, pr Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		paths, err := s.Files(pr, pr.Context.WorkingDirectory())
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) && !s.Required {
+				continue
+			} else if err != nil {
+				result <- Result{nil, err, nil}
+				return
+			}
+			vars, err := dotenv.Parse(string(data), func(name string) (string, bool) {
+				return LookupEnv(pr.Context.GetEnv(), name)
+			})
+			if err != nil {
+				result <- Result{nil, fmt.Errorf("%s: %w", path, err), nil}
+				return
+			}
+			for _, v := range vars {
+				pr.Context.AddEnv(v.Key + "=" + v.Value)
+			}
+		}
+		result <- Result{}
+	}()
+	return result
+}
+
+// Files returns the paths of the files that the step reads, with variable references interpolated and relative paths resolved against dir.
+func (s EnvFileStep) Files(pr Result, dir string) ([]string, error) {
+	var paths []string
+	for _, path := range s.Paths {
+		path, err := Interpolate(path, pr)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// String returns the files that the step reads.
+func (s EnvFileStep) String() string {
+	return "envfile " + strings.Join(s.Paths, " ")
+}
@@ -2,33 +2,239 @@ package steps
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"syscall"
+	"time"
 )
 
-// ExecStep handles executing a command.
+// DefaultGracePeriod is how long a process is given to exit after being asked to stop before it is forcibly killed.
+var DefaultGracePeriod = 5 * time.Second
+
+// ExecStep handles executing a command. Unless it is interactive, the command is started in its own process group so that stopping it also stops any processes it started.
 type ExecStep struct {
-	Args []interface{}
+	Args        []interface{}
+	StopSignal  os.Signal     // The signal used to ask the process to stop. Defaults to SIGTERM.
+	GracePeriod time.Duration // How long to wait after sending StopSignal before sending SIGKILL. 0 sends SIGKILL immediately.
+	ExitCodes   []int         // Additional exit codes, besides 0, that are treated as success.
+	Stdout      *Output       // Where standard output is written. Defaults to os.Stdout.
+	Stderr      *Output       // Where standard error is written. Defaults to os.Stderr.
+	MergeStderr bool          // Whether standard error is written to the same destination and capture as standard output.
+	Stdin       *Input        // Where standard input is read from. Defaults to no input.
+	Env         []string      // Additional environment variables, in the form "KEY=value".
+	Interactive bool          // Whether the command runs in gobl's process group, so that it can read from the terminal.
+}
+
+// ExecResult is the result of a command run by an ExecStep.
+type ExecResult struct {
+	Command  string
+	Args     []string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+	Pid      int
+}
+
+// String returns the command's standard output.
+func (r *ExecResult) String() string {
+	return r.Stdout
+}
+
+// ExecError is returned when a command exits with an unsuccessful exit code. It can be retrieved from a Catch's error with errors.As.
+type ExecError struct {
+	Result *ExecResult
+	Err    error
+}
+
+func (e *ExecError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying *exec.ExitError.
+func (e *ExecError) Unwrap() error {
+	return e.Err
 }
 
-// Run runs a command.
-func (s ExecStep) Run(pr Result) chan Result {
+// Run runs a command, returning an *ExecResult on success and an *ExecError if the command exits with an unsuccessful exit code. If the context is cancelled, the process is stopped and the context's error is returned once it has exited.
+func (s *ExecStep) Run(ctx context.Context, pr Result) chan Result {
 	result := make(chan Result)
 
-	killSignal := make(chan Result)
-	doneSignal := make(chan Result)
+	// Set up buffers for capturing output.
+	var stdout, stderr bytes.Buffer
+
+	go func() {
+		cmd, err := s.command(pr)
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		closeOutputs, err := s.setOutputs(cmd, &stdout, &stderr)
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		defer closeOutputs()
+		stdin, closeStdin, err := s.Stdin.open(cmd.Dir, pr)
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		defer closeStdin()
+		cmd.Stdin = stdin
+		start := time.Now()
+		if err := cmd.Start(); err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		doneSignal := make(chan error, 1)
+		go func() {
+			doneSignal <- cmd.Wait()
+		}()
+		// Wait for either the command to finish or the context to be cancelled.
+		select {
+		case err := <-doneSignal:
+			r := newExecResult(cmd, &stdout, &stderr, start)
+			if err != nil && !s.isSuccess(r.ExitCode) {
+				result <- Result{nil, &ExecError{Result: r, Err: err}, nil}
+				return
+			}
+			result <- Result{r, nil, nil}
+		case <-ctx.Done():
+			stop, err := s.stop(cmd, doneSignal)
+			if err != nil {
+				result <- Result{nil, err, nil}
+				return
+			}
+			if report := StopReportFrom(ctx); report != nil {
+				report.add(stop)
+			}
+			result <- Result{nil, ctx.Err(), nil}
+		}
+	}()
+	return result
+}
 
-	pr.Context.AddProcessKillChannel(killSignal)
+// command creates the step's command, set up to run in the context's working directory and environment.
+func (s *ExecStep) command(pr Result) (*exec.Cmd, error) {
+	args, err := s.Expand(pr)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = pr.Context.WorkingDirectory()
+	cmd.Env = append(pr.Context.GetEnv(), s.Env...)
+	if !s.Interactive {
+		setProcessGroup(cmd)
+	}
+	return cmd, nil
+}
 
-	// Set up buffer for capturing output.
-	var buffer bytes.Buffer
-	mw := io.MultiWriter(os.Stdout, &buffer)
+// newExecResult returns the result of a command that has exited.
+func newExecResult(cmd *exec.Cmd, stdout, stderr *bytes.Buffer, start time.Time) *ExecResult {
+	return &ExecResult{
+		Command:  cmd.Args[0],
+		Args:     cmd.Args[1:],
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+		Pid:      cmd.Process.Pid,
+	}
+}
 
+// setOutputs opens the step's output destinations and sets them on the command, along with the buffers that capture them. The returned function closes any opened files.
+func (s *ExecStep) setOutputs(cmd *exec.Cmd, stdout, stderr *bytes.Buffer) (func(), error) {
+	var closers []func() error
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	w, c, err := s.Stdout.open(cmd.Dir, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	closers = append(closers, c)
+	cmd.Stdout = io.MultiWriter(w, stdout)
+	if s.MergeStderr {
+		cmd.Stderr = cmd.Stdout
+		return closeAll, nil
+	}
+	w, c, err = s.Stderr.open(cmd.Dir, os.Stderr)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	closers = append(closers, c)
+	cmd.Stderr = io.MultiWriter(w, stderr)
+	return closeAll, nil
+}
+
+// isSuccess returns if the exit code is one of the step's additional ExitCodes.
+func (s *ExecStep) isSuccess(code int) bool {
+	for _, c := range s.ExitCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// stop asks the command's process group to stop with the step's StopSignal, killing it if it has not exited within the step's GracePeriod. It returns once the command has exited.
+func (s *ExecStep) stop(cmd *exec.Cmd, done chan error) (Stop, error) {
+	stop := Stop{
+		Command:     strings.Join(cmd.Args, " "),
+		Pid:         cmd.Process.Pid,
+		Signal:      s.StopSignal,
+		GracePeriod: s.GracePeriod,
+	}
+	if stop.Signal == nil {
+		stop.Signal = syscall.SIGTERM
+	}
+	start := time.Now()
+	if s.GracePeriod > 0 {
+		if err := signalProcessGroup(cmd, stop.Signal); err == nil {
+			select {
+			case <-done:
+				stop.Duration = time.Since(start)
+				return stop, nil
+			case <-time.After(s.GracePeriod):
+			}
+		}
+	}
+	stop.Forced = true
+	if err := killProcessGroup(cmd); err != nil {
+		return stop, err
+	}
+	<-done
+	stop.Duration = time.Since(start)
+	return stop, nil
+}
+
+// Expand returns the step's arguments converted to strings, with any variable references in string arguments interpolated.
+func (s *ExecStep) Expand(pr Result) ([]string, error) {
+	args, err := interpolateArgs(s.Args, pr)
+	if err != nil {
+		return nil, err
+	}
+	return stringArgs(args), nil
+}
+
+// Command returns the step's arguments converted to strings.
+func (s *ExecStep) Command() []string {
+	return stringArgs(s.Args)
+}
+
+// stringArgs converts interface arguments to strings.
+func stringArgs(interfaceArgs []interface{}) []string {
 	var args []string
 	// Convert interface arguments to real arguments.
-	for _, a := range s.Args {
+	for _, a := range interfaceArgs {
 		// First dereference pointer types. TODO: Probably replace with reflect.
 		var t interface{}
 		switch v := a.(type) {
@@ -101,39 +307,10 @@ func (s ExecStep) Run(pr Result) chan Result {
 			args = append(args, fmt.Sprintf("%v", v))
 		}
 	}
+	return args
+}
 
-	// Create and set up our command before spawning goroutines
-	cmd := exec.Command(args[0], args[1:]...)
-	cmd.Stdout = mw
-	cmd.Stderr = os.Stderr
-	cmd.Dir = pr.Context.WorkingDirectory()
-	cmd.Env = pr.Context.GetEnv()
-
-	// Loop for either our doneSignal or our external kill signal
-	go func() {
-		select {
-		case <-killSignal:
-			if err := cmd.Process.Kill(); err != nil {
-				result <- Result{nil, err, nil}
-				return
-			}
-			result <- Result{"killed", nil, nil}
-		case r := <-doneSignal:
-			result <- r
-		}
-		pr.Context.RemoveProcessKillChannel(killSignal)
-	}()
-	// Start and wait for our command.
-	go func() {
-		if err := cmd.Start(); err != nil {
-			doneSignal <- Result{nil, err, nil}
-			return
-		}
-		if err := cmd.Wait(); err != nil {
-			doneSignal <- Result{nil, err, nil}
-			return
-		}
-		doneSignal <- Result{buffer.String(), nil, nil}
-	}()
-	return result
+// String returns the command line that the step runs.
+func (s *ExecStep) String() string {
+	return "exec " + strings.Join(s.Command(), " ")
 }
@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package steps
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// hasProcessGroup reports whether the command was started in its own process group.
+func hasProcessGroup(cmd *exec.Cmd) bool {
+	return cmd.SysProcAttr != nil && cmd.SysProcAttr.Setpgid
+}
+
+// signalProcessGroup sends a signal to the command's process group, or to the command alone if it has none.
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok || !hasProcessGroup(cmd) {
+		return cmd.Process.Signal(sig)
+	}
+	return syscall.Kill(-cmd.Process.Pid, s)
+}
+
+// killProcessGroup sends SIGKILL to the command's process group, or to the command alone if it has none.
+func killProcessGroup(cmd *exec.Cmd) error {
+	pid := cmd.Process.Pid
+	if hasProcessGroup(cmd) {
+		pid = -pid
+	}
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
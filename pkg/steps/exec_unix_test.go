@@ -0,0 +1,48 @@
+//go:build !windows
+// +build !windows
+
+package steps
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExecProcessGroup(t *testing.T) {
+	for _, interactive := range []bool{false, true} {
+		s := &ExecStep{Args: []interface{}{"sh", "-c", "ps -o pgid= -p $$"}, Stdout: &Output{}, Interactive: interactive}
+		r := <-s.Run(context.Background(), Result{Context: &testContext{env: os.Environ()}})
+		if r.Error != nil {
+			t.Skip(r.Error)
+		}
+		pgid, err := strconv.Atoi(strings.TrimSpace(r.Result.(*ExecResult).Stdout))
+		if err != nil {
+			t.Skip(err)
+		}
+		if shared := pgid == syscall.Getpgrp(); shared != interactive {
+			t.Errorf("interactive %v: command shares gobl's process group: %v", interactive, shared)
+		}
+	}
+}
+
+func TestExecStopsInteractiveCommand(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &ExecStep{Args: []interface{}{"sleep", "10"}, GracePeriod: time.Second, Interactive: true}
+	result := s.Run(ctx, Result{Context: &testContext{env: os.Environ()}})
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case r := <-result:
+		if !errors.Is(r.Error, context.Canceled) {
+			t.Errorf("expected the step to be cancelled, got %v", r.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("interactive command was not stopped")
+	}
+}
@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package steps
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// signalProcessGroup is not supported on Windows, so processes are always killed.
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	return errors.New("sending signals is not supported on windows")
+}
+
+// killProcessGroup kills the command's process tree.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package steps
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 )
@@ -10,12 +11,17 @@ type ExistsStep struct {
 	Path string
 }
 
-// Run checks if the file or folder exists and returns an fs.FileInfo.
-func (s ExistsStep) Run(pr Result) chan Result {
+// Run checks if the file or folder exists and returns an fs.FileInfo. Any variable references in the path are interpolated.
+func (s ExistsStep) Run(ctx context.Context, pr Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
-		info, err := os.Stat(filepath.Join(pr.Context.WorkingDirectory(), s.Path))
+		path, err := Interpolate(s.Path, pr)
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		info, err := os.Stat(filepath.Join(pr.Context.WorkingDirectory(), path))
 		if err != nil {
 			result <- Result{nil, err, nil}
 			return
@@ -25,3 +31,8 @@ func (s ExistsStep) Run(pr Result) chan Result {
 
 	return result
 }
+
+// String returns the path that the step checks.
+func (s ExistsStep) String() string {
+	return "exists " + s.Path
+}
@@ -0,0 +1,66 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultForEachVar is the variable that holds the current item of a ForEachStep if no other is given.
+const DefaultForEachVar = "ITEM"
+
+// Items returns the items that a ForEachStep iterates over.
+type Items func(Result) ([]string, error)
+
+// ForEachStep begins a block of steps that runs once for each of its items, ending with an EndForEachStep. Each iteration runs with its own copy of the task's context, in which Var is set to the current item.
+type ForEachStep struct {
+	Items       Items
+	Description string
+	Var         string
+	Parallel    bool // Whether iterations run at the same time rather than in order.
+	Limit       int  // The maximum number of parallel iterations, or 0 for no limit.
+}
+
+// Run returns the items to iterate over as the result.
+func (s ForEachStep) Run(ctx context.Context, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		items, err := s.Items(r)
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		result <- Result{items, nil, nil}
+	}()
+	return result
+}
+
+// String returns "foreach" followed by the description of the items, the variable, and how the iterations run.
+func (s ForEachStep) String() string {
+	str := fmt.Sprintf("foreach %s (as %s", s.Description, s.Var)
+	if s.Parallel {
+		if s.Limit > 0 {
+			str += fmt.Sprintf(", %d at a time", s.Limit)
+		} else {
+			str += ", in parallel"
+		}
+	}
+	return str + ")"
+}
+
+// EndForEachStep represents the end of a ForEachStep's block.
+type EndForEachStep struct {
+}
+
+// Run just returns an empty result.
+func (s EndForEachStep) Run(ctx context.Context, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		result <- Result{}
+	}()
+	return result
+}
+
+// String returns "endforeach".
+func (s EndForEachStep) String() string {
+	return "endforeach"
+}
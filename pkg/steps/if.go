@@ -0,0 +1,89 @@
+package steps
+
+import (
+	"context"
+)
+
+// Condition reports whether the steps of a conditional block should run.
+type Condition func(Result) (bool, error)
+
+// IfStep begins a block of steps that only run if its condition is true. The block may be followed by ElseIfSteps and an ElseStep, and ends with an EndIfStep.
+type IfStep struct {
+	Condition   Condition
+	Description string
+}
+
+// Run evaluates the condition, returning whether it is true as the result.
+func (s IfStep) Run(ctx context.Context, r Result) chan Result {
+	return runCondition(s.Condition, r)
+}
+
+// String returns "if" followed by the condition's description.
+func (s IfStep) String() string {
+	return "if " + s.Description
+}
+
+// ElseIfStep begins a block of steps that only run if the conditions of the preceding IfStep and ElseIfSteps are false and its own condition is true.
+type ElseIfStep struct {
+	Condition   Condition
+	Description string
+}
+
+// Run evaluates the condition, returning whether it is true as the result.
+func (s ElseIfStep) Run(ctx context.Context, r Result) chan Result {
+	return runCondition(s.Condition, r)
+}
+
+// String returns "else if" followed by the condition's description.
+func (s ElseIfStep) String() string {
+	return "else if " + s.Description
+}
+
+// ElseStep begins a block of steps that only run if the conditions of the preceding IfStep and ElseIfSteps are false.
+type ElseStep struct {
+}
+
+// Run just returns an empty result.
+func (s ElseStep) Run(ctx context.Context, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		result <- Result{}
+	}()
+	return result
+}
+
+// String returns "else".
+func (s ElseStep) String() string {
+	return "else"
+}
+
+// EndIfStep represents the end of an IfStep's block.
+type EndIfStep struct {
+}
+
+// Run just returns an empty result.
+func (s EndIfStep) Run(ctx context.Context, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		result <- Result{}
+	}()
+	return result
+}
+
+// String returns "endif".
+func (s EndIfStep) String() string {
+	return "endif"
+}
+
+func runCondition(c Condition, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		ok, err := c(r)
+		if err != nil {
+			result <- Result{nil, err, nil}
+			return
+		}
+		result <- Result{ok, nil, nil}
+	}()
+	return result
+}
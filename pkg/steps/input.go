@@ -0,0 +1,57 @@
+package steps
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Input is a source for a command's standard input.
+type Input struct {
+	Text   string // The text to read. Ignored if Path or Result is set.
+	Path   string // The file to read, relative to the working directory.
+	Result bool   // Whether to read the previous step's result.
+}
+
+// open returns the reader for the input, or nil if the input is nil. The returned closer must be called once the command has finished.
+func (i *Input) open(dir string, pr Result) (io.Reader, func() error, error) {
+	noop := func() error { return nil }
+	switch {
+	case i == nil:
+		return nil, noop, nil
+	case i.Path != "":
+		path := i.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f.Close, nil
+	case i.Result:
+		switch r := pr.Result.(type) {
+		case nil:
+			return strings.NewReader(""), noop, nil
+		case []byte:
+			return bytes.NewReader(r), noop, nil
+		default:
+			return strings.NewReader(fmt.Sprint(r)), noop, nil
+		}
+	}
+	return strings.NewReader(i.Text), noop, nil
+}
+
+// String returns a shell-like description of the input's source.
+func (i *Input) String() string {
+	switch {
+	case i.Path != "":
+		return "< " + i.Path
+	case i.Result:
+		return "< result"
+	}
+	return "<<< " + fmt.Sprintf("%q", i.Text)
+}
@@ -1,19 +1,39 @@
 package steps
 
+import "context"
+
 // Context is an interface to Context.
 type Context interface {
-	AddProcessKillChannel(chan Result)
-	RemoveProcessKillChannel(chan Result)
-	GetProcessKillChannels() []chan Result
 	GetEnv() []string
 	AddEnv(...string)
-	RunTask(string) chan Result
+	UnsetEnv(...string)
+	ClearEnv(...string)
+	Reset([]string)
+	RunTask(context.Context, string) chan Result
 	WorkingDirectory() string
 	SetWorkingDirectory(string)
 	UpdateWorkingDirectory(string)
+	Var(string) (string, bool)
+	SetVar(string, string)
 }
 
-// Step is the interface that all gobl steps adhere to.
+// Step is the interface that all gobl steps adhere to. Steps should stop and return a Result with the context's error when the provided context is cancelled.
 type Step interface {
-	Run(Result) chan Result
+	Run(context.Context, Result) chan Result
+}
+
+// Wrapper is implemented by steps that modify the behavior of another step.
+type Wrapper interface {
+	Unwrap() Step
+}
+
+// Unwrap returns the innermost step of any Wrappers.
+func Unwrap(s Step) Step {
+	for {
+		w, ok := s.(Wrapper)
+		if !ok {
+			return s
+		}
+		s = w.Unwrap()
+	}
 }
@@ -0,0 +1,134 @@
+package steps
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kettek/gobl/pkg/shell"
+)
+
+// Raw is a string argument that is used as is, without interpolating variable references.
+type Raw string
+
+// Interpolate replaces "${NAME}" and "{{.NAME}}" references in s with the values of variables.
+func Interpolate(s string, pr Result) (string, error) {
+	return interpolate(s, pr, true)
+}
+
+// InterpolateDefined is like Interpolate, but leaves references to undefined variables as is. It is intended for showing what a step would do before it runs.
+func InterpolateDefined(s string, pr Result) string {
+	v, err := interpolate(s, pr, false)
+	if err != nil {
+		return s
+	}
+	return v
+}
+
+func interpolate(s string, pr Result, strict bool) (string, error) {
+	if !strings.ContainsAny(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			b.WriteString("${")
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i:], '}')
+			if end == -1 {
+				return "", fmt.Errorf("unterminated \"${\" in %q", s)
+			}
+			name := s[i+2 : i+end]
+			if !shell.IsName(name) {
+				return "", fmt.Errorf("invalid variable name %q in %q", name, s)
+			}
+			v, ok := LookupVar(pr, name)
+			if !ok && strict {
+				return "", fmt.Errorf("undefined variable %q in %q", name, s)
+			} else if !ok {
+				v = s[i : i+end+1]
+			}
+			b.WriteString(v)
+			i += end
+		case strings.HasPrefix(s[i:], "{{"):
+			end := strings.Index(s[i:], "}}")
+			name := ""
+			if end != -1 {
+				name = strings.TrimSpace(s[i+2 : i+end])
+			}
+			// Only "{{.NAME}}" references to defined variables are replaced. Any other braces, such as a Go template passed to a command, are left alone.
+			v, ok := "", false
+			if strings.HasPrefix(name, ".") && shell.IsName(name[1:]) {
+				v, ok = LookupVar(pr, name[1:])
+			}
+			if !ok {
+				b.WriteString("{{")
+				i++
+				continue
+			}
+			b.WriteString(v)
+			i += end + 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// LookupVar returns the value of the named variable. Variables are looked up in the Context's variables, which include the task's parameters, then in the Context's environment. "RESULT" refers to the previous step's result if no such variable exists.
+func LookupVar(pr Result, name string) (string, bool) {
+	if pr.Context != nil {
+		if v, ok := pr.Context.Var(name); ok {
+			return v, true
+		}
+		if v, ok := LookupEnv(pr.Context.GetEnv(), name); ok {
+			return v, true
+		}
+	}
+	if name == "RESULT" {
+		return ResultString(pr.Result), true
+	}
+	return "", false
+}
+
+// LookupEnv returns the value of the named variable in env, where later entries take precedence.
+func LookupEnv(env []string, name string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], name+"=") {
+			return env[i][len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+// ResultString returns a step's result as a string. The output of an Exec step has its trailing newlines removed, as with a shell's command substitution.
+func ResultString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case *ExecResult:
+		return strings.TrimRight(v.Stdout, "\r\n")
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// interpolateArgs interpolates each string argument, leaving arguments of other types, including Raw, as is.
+func interpolateArgs(args []interface{}, pr Result) ([]interface{}, error) {
+	out := make([]interface{}, len(args))
+	for i, a := range args {
+		if s, ok := a.(string); ok {
+			v, err := Interpolate(s, pr)
+			if err != nil {
+				return nil, err
+			}
+			a = v
+		}
+		out[i] = a
+	}
+	return out, nil
+}
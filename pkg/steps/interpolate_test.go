@@ -0,0 +1,57 @@
+package steps
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	pr := Result{Result: "v1", Error: nil, Context: nil}
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"${RESULT}", "v1"},
+		{"{{.RESULT}}", "v1"},
+		{"{{ .RESULT }}-x", "v1-x"},
+		{"{{.Dir}}", "{{.Dir}}"},
+		{"{{json .}}", "{{json .}}"},
+		{"echo $$ $HOME", "echo $$ $HOME"},
+		{"$${RESULT}", "${RESULT}"},
+		{"a$$b", "a$$b"},
+	}
+	for _, tt := range tests {
+		got, err := Interpolate(tt.in, pr)
+		if err != nil {
+			t.Errorf("Interpolate(%q): %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("Interpolate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateUndefined(t *testing.T) {
+	pr := Result{Result: nil, Error: nil, Context: nil}
+	if _, err := Interpolate("${MISSING}", pr); err == nil {
+		t.Error("expected an error for an undefined ${} variable")
+	}
+	if got := InterpolateDefined("${MISSING}/x", pr); got != "${MISSING}/x" {
+		t.Errorf("InterpolateDefined = %q, want %q", got, "${MISSING}/x")
+	}
+}
+
+func TestEnvInterpolatesValues(t *testing.T) {
+	c := &testContext{vars: map[string]string{"ITEM": "linux"}}
+	r := <-(EnvStep{Args: []string{"GOOS=${ITEM}", "LITERAL=$${ITEM}", "SHELL=$HOME"}}).Run(context.Background(), Result{Context: c})
+	if r.Error != nil {
+		t.Fatal(r.Error)
+	}
+	want := []string{"GOOS=linux", "LITERAL=${ITEM}", "SHELL=$HOME"}
+	if strings.Join(c.env, " ") != strings.Join(want, " ") {
+		t.Errorf("got %q, want %q", c.env, want)
+	}
+	if r := <-(EnvStep{Args: []string{"A=${UNDEFINED}"}}).Run(context.Background(), Result{Context: c}); r.Error == nil {
+		t.Error("expected an error for an undefined variable")
+	}
+}
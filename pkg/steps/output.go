@@ -0,0 +1,56 @@
+package steps
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Output is a destination for one of a command's output streams. The stream is still captured into the command's ExecResult.
+type Output struct {
+	Writer io.Writer // The writer to write to, or nil to discard the output. Ignored if Path is set.
+	Path   string    // The file to write to, relative to the working directory.
+	Append bool      // Whether to append to Path rather than truncating it.
+}
+
+// DiscardOutput is an Output that discards everything written to it.
+var DiscardOutput = &Output{Writer: io.Discard}
+
+// open returns the writer for the output, or def if the output is nil. The returned closer must be called once the command has finished.
+func (o *Output) open(dir string, def io.Writer) (io.Writer, func() error, error) {
+	if o == nil {
+		return def, func() error { return nil }, nil
+	}
+	if o.Path == "" {
+		if o.Writer == nil {
+			return io.Discard, func() error { return nil }, nil
+		}
+		return o.Writer, func() error { return nil }, nil
+	}
+	path := o.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if o.Append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// String returns a shell-like description of the output's destination.
+func (o *Output) String() string {
+	switch {
+	case o.Path != "" && o.Append:
+		return ">> " + o.Path
+	case o.Path != "":
+		return "> " + o.Path
+	case o.Writer == nil || o.Writer == io.Discard:
+		return "> /dev/null"
+	}
+	return "> writer"
+}
@@ -0,0 +1,18 @@
+package steps
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExecNilWriterDiscards(t *testing.T) {
+	s := &ExecStep{Args: []interface{}{"echo", "hello"}, Stdout: &Output{}, Stderr: &Output{}}
+	ctx := &testContext{dir: t.TempDir()}
+	r := <-s.Run(context.Background(), Result{Result: nil, Error: nil, Context: ctx})
+	if r.Error != nil {
+		t.Fatal(r.Error)
+	}
+	if out := ResultString(r.Result); out != "hello" {
+		t.Errorf("captured %q, want %q", out, "hello")
+	}
+}
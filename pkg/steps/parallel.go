@@ -1,6 +1,8 @@
 package steps
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -8,7 +10,8 @@ import (
 
 // ParallelStep runs tasks in parallel.
 type ParallelStep struct {
-	TaskNames []string
+	TaskNames  []string
+	InheritEnv bool // Whether the tasks start with this task's environment rather than the process's.
 }
 
 type parallelOperation struct {
@@ -17,35 +20,44 @@ type parallelOperation struct {
 	result     Result
 }
 
-// Run uses wait groups.
-func (s ParallelStep) Run(r Result) chan Result {
+// Run uses wait groups. If any task fails, the remaining tasks are cancelled.
+func (s ParallelStep) Run(ctx context.Context, r Result) chan Result {
 	var wg sync.WaitGroup
 	parallelResult := make(chan Result)
 	var parallelOperations []*parallelOperation
+	ctx, cancel := context.WithCancel(taskEnv(ctx, r, s.InheritEnv))
 
 	for _, t := range s.TaskNames {
 		taskResult := &parallelOperation{
 			name:       t,
-			runChannel: r.Context.RunTask(t),
+			runChannel: r.Context.RunTask(ctx, t),
 		}
 		parallelOperations = append(parallelOperations, taskResult)
 		wg.Add(1)
 		go func(pOp *parallelOperation) {
 			defer wg.Done()
 			pOp.result = <-pOp.runChannel
+			if pOp.result.Error != nil {
+				cancel()
+			}
 		}(taskResult)
 	}
 	go func() {
 		wg.Wait()
+		parentErr := ctx.Err()
+		cancel()
 		var err error
 		var errStrings []string
 		for _, pr := range parallelOperations {
-			if pr.result.Error != nil {
+			// Skip tasks that were only cancelled because a sibling failed.
+			if pr.result.Error != nil && !errors.Is(pr.result.Error, context.Canceled) {
 				errStrings = append(errStrings, fmt.Sprintf("%s -> %s", pr.name, pr.result.Error))
 			}
 		}
 		if len(errStrings) > 0 {
 			err = fmt.Errorf(strings.Join(errStrings, ","))
+		} else if parentErr != nil {
+			err = parentErr
 		}
 		parallelResult <- Result{
 			Result:  parallelOperations,
@@ -55,3 +67,8 @@ func (s ParallelStep) Run(r Result) chan Result {
 	}()
 	return parallelResult
 }
+
+// String returns the names of the tasks that the step runs.
+func (s ParallelStep) String() string {
+	return "parallel " + strings.Join(s.TaskNames, ", ")
+}
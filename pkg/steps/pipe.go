@@ -0,0 +1,156 @@
+package steps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PipeStep runs several commands at once, connecting each command's standard output to the next command's standard input like a shell pipeline.
+type PipeStep struct {
+	Commands []*ExecStep
+	Stdin    *Input // Where the first command's standard input is read from.
+}
+
+// Run runs the pipeline, returning the last command's *ExecResult once all of the commands have exited. As with a shell's pipefail option, the pipeline fails with the *ExecError of the last command to exit unsuccessfully, if any. If the context is cancelled, all of the commands are stopped.
+func (s *PipeStep) Run(ctx context.Context, pr Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		result <- s.run(ctx, pr)
+	}()
+	return result
+}
+
+func (s *PipeStep) run(ctx context.Context, pr Result) Result {
+	if len(s.Commands) == 0 {
+		return Result{nil, errors.New("pipe has no commands"), nil}
+	}
+	n := len(s.Commands)
+	stdouts := make([]bytes.Buffer, n)
+	stderrs := make([]bytes.Buffer, n)
+	var closers []func() error
+	defer func() {
+		for _, c := range closers {
+			c()
+		}
+	}()
+
+	cmds := make([]*execCmd, n)
+	for i, c := range s.Commands {
+		cmd, err := c.command(pr)
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		closeOutputs, err := c.setOutputs(cmd, &stdouts[i], &stderrs[i])
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		closers = append(closers, func() error { closeOutputs(); return nil })
+		cmds[i] = &execCmd{step: c, cmd: cmd}
+	}
+	stdin, closeStdin, err := s.Stdin.open(cmds[0].cmd.Dir, pr)
+	if err != nil {
+		return Result{nil, err, nil}
+	}
+	closers = append(closers, closeStdin)
+	cmds[0].cmd.Stdin = stdin
+
+	// Connect each command to the next. The parent's copies of the pipe are closed once the commands have started, so that each reader sees the end of its input when its writer exits.
+	var pipes []*os.File
+	for i := 0; i < n-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeFiles(pipes)
+			return Result{nil, err, nil}
+		}
+		pipes = append(pipes, r, w)
+		cmds[i].cmd.Stdout = w
+		if cmds[i].step.MergeStderr {
+			cmds[i].cmd.Stderr = w
+		}
+		cmds[i+1].cmd.Stdin = r
+	}
+
+	start := time.Now()
+	for i, c := range cmds {
+		if err := c.cmd.Start(); err != nil {
+			closeFiles(pipes)
+			for _, started := range cmds[:i] {
+				killProcessGroup(started.cmd)
+				started.cmd.Wait()
+			}
+			return Result{nil, err, nil}
+		}
+	}
+	closeFiles(pipes)
+
+	var wg sync.WaitGroup
+	var stopErr error
+	var stopLock sync.Mutex
+	for _, c := range cmds {
+		wg.Add(1)
+		go func(c *execCmd) {
+			defer wg.Done()
+			done := make(chan error, 1)
+			go func() {
+				done <- c.cmd.Wait()
+			}()
+			select {
+			case c.err = <-done:
+			case <-ctx.Done():
+				stop, err := c.step.stop(c.cmd, done)
+				if err != nil {
+					stopLock.Lock()
+					stopErr = err
+					stopLock.Unlock()
+					return
+				}
+				if report := StopReportFrom(ctx); report != nil {
+					report.add(stop)
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if stopErr != nil {
+		return Result{nil, stopErr, nil}
+	}
+	if ctx.Err() != nil {
+		return Result{nil, ctx.Err(), nil}
+	}
+	for i := n - 1; i >= 0; i-- {
+		r := newExecResult(cmds[i].cmd, &stdouts[i], &stderrs[i], start)
+		if cmds[i].err != nil && !cmds[i].step.isSuccess(r.ExitCode) {
+			return Result{nil, &ExecError{Result: r, Err: cmds[i].err}, nil}
+		}
+	}
+	return Result{newExecResult(cmds[n-1].cmd, &stdouts[n-1], &stderrs[n-1], start), nil, nil}
+}
+
+// execCmd is a running command of a pipeline.
+type execCmd struct {
+	step *ExecStep
+	cmd  *exec.Cmd
+	err  error
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// String returns the pipeline's command lines separated by "|".
+func (s *PipeStep) String() string {
+	var cmds []string
+	for _, c := range s.Commands {
+		cmds = append(cmds, strings.Join(c.Command(), " "))
+	}
+	return "pipe " + strings.Join(cmds, " | ")
+}
@@ -1,24 +1,40 @@
 package steps
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // PrintStep handles printing passed arguments or the results of the previous step if no arguments are passed.
 type PrintStep struct {
 	Args []interface{}
 }
 
-// Run prints the contents of the print step.
-func (s PrintStep) Run(r Result) chan Result {
+// Run prints the contents of the print step, interpolating any variable references in string arguments.
+func (s PrintStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
 		if len(s.Args) == 0 {
 			fmt.Println(r.Result)
 		} else {
-			fmt.Println(s.Args...)
+			args, err := interpolateArgs(s.Args, r)
+			if err != nil {
+				result <- Result{nil, err, nil}
+				return
+			}
+			fmt.Println(args...)
 		}
 		result <- Result{}
 	}()
 
 	return result
 }
+
+// String returns what the step prints.
+func (s PrintStep) String() string {
+	if len(s.Args) == 0 {
+		return "print result"
+	}
+	return "print " + fmt.Sprint(s.Args...)
+}
@@ -1,8 +1,14 @@
 package steps
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // PromptStep handles printing passed arguments or the results of the previous step if no arguments are passed.
@@ -10,11 +16,19 @@ type PromptStep struct {
 	Message string
 }
 
-// Run prints the contents of the print step.
-func (s PromptStep) Run(r Result) chan Result {
+// Run prints the contents of the print step. If the context is cancelled while waiting for input, the context's error is returned.
+func (s PromptStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
+		// Only one prompt asks for input at a time.
+		select {
+		case promptLock <- struct{}{}:
+			defer func() { <-promptLock }()
+		case <-ctx.Done():
+			result <- Result{Error: ctx.Err()}
+			return
+		}
 		isYes := false
 		for true {
 			if len(s.Message) == 0 {
@@ -22,15 +36,16 @@ func (s PromptStep) Run(r Result) chan Result {
 			} else {
 				fmt.Print(s.Message, " (y/n) ")
 			}
-			var in string
-			if _, err := fmt.Scan(&in); err != nil {
+			in, err := stdin.readLine(ctx)
+			if err != nil {
 				result <- Result{Error: err}
 				return
 			}
-			if strings.HasPrefix(strings.ToLower(in), "y") {
+			in = strings.ToLower(strings.TrimSpace(in))
+			if strings.HasPrefix(in, "y") {
 				isYes = true
 				break
-			} else if strings.HasPrefix(strings.ToLower(in), "n") {
+			} else if strings.HasPrefix(in, "n") {
 				break
 			}
 		}
@@ -40,12 +55,59 @@ func (s PromptStep) Run(r Result) chan Result {
 	return result
 }
 
+var promptLock = make(chan struct{}, 1)
+
+// stdin reads the lines answered to prompts. Reads are shared by all prompts for the life of the process, so that a read left waiting by a cancelled prompt delivers its line to the next prompt rather than losing it.
+var stdin = &lineReader{}
+
+type lineReader struct {
+	lock    sync.Mutex
+	r       *bufio.Reader
+	pending chan readLineResult // A read that is in progress or whose line has not yet been returned.
+}
+
+type readLineResult struct {
+	line string
+	err  error
+}
+
+// readLine returns the next line of standard input, waiting until it is read or the context is cancelled. A line read after the context is cancelled is returned by the next call.
+func (l *lineReader) readLine(ctx context.Context) (string, error) {
+	l.lock.Lock()
+	if l.r == nil {
+		l.r = bufio.NewReader(os.Stdin)
+	}
+	if l.pending == nil {
+		pending := make(chan readLineResult, 1)
+		l.pending = pending
+		go func() {
+			line, err := l.r.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			pending <- readLineResult{line, err}
+		}()
+	}
+	pending := l.pending
+	l.lock.Unlock()
+
+	select {
+	case r := <-pending:
+		l.lock.Lock()
+		l.pending = nil
+		l.lock.Unlock()
+		return r.line, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
 // EndStep represents the end of a prompt.
 type EndStep struct {
 }
 
 // Run just returns an empty result.
-func (s EndStep) Run(r Result) chan Result {
+func (s EndStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
@@ -60,7 +122,7 @@ type YesStep struct {
 }
 
 // Run returns true in the result.
-func (s YesStep) Run(r Result) chan Result {
+func (s YesStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
@@ -75,7 +137,7 @@ type NoStep struct {
 }
 
 // Run returns false in the result.
-func (s NoStep) Run(r Result) chan Result {
+func (s NoStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
@@ -84,3 +146,26 @@ func (s NoStep) Run(r Result) chan Result {
 
 	return result
 }
+
+// String returns the step's prompt.
+func (s PromptStep) String() string {
+	if len(s.Message) == 0 {
+		return "prompt result"
+	}
+	return "prompt " + strconv.Quote(s.Message)
+}
+
+// String returns "end".
+func (s EndStep) String() string {
+	return "end"
+}
+
+// String returns "yes".
+func (s YesStep) String() string {
+	return "yes"
+}
+
+// String returns "no".
+func (s NoStep) String() string {
+	return "no"
+}
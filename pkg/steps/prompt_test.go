@@ -0,0 +1,31 @@
+package steps
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLineReaderKeepsLineOfCancelledRead(t *testing.T) {
+	pr, pw := io.Pipe()
+	l := &lineReader{r: bufio.NewReader(pr)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.readLine(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the context's error, got %v", err)
+	}
+
+	go pw.Write([]byte("yes\nno\n"))
+	for _, want := range []string{"yes\n", "no\n"} {
+		line, err := l.readLine(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Errorf("read %q, want %q", line, want)
+		}
+	}
+}
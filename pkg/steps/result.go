@@ -1,5 +1,7 @@
 package steps
 
+import "context"
+
 // Result represents the result of a step.
 type Result struct {
 	Result  interface{}
@@ -13,7 +15,7 @@ type ResultStep struct {
 }
 
 // Run calls the step's result function.
-func (s ResultStep) Run(r Result) chan Result {
+func (s ResultStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 	go func() {
 		s.Func(r.Result)
@@ -21,3 +23,8 @@ func (s ResultStep) Run(r Result) chan Result {
 	}()
 	return result
 }
+
+// String returns "result".
+func (s ResultStep) String() string {
+	return "result"
+}
@@ -0,0 +1,107 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+)
+
+// Backoff returns how long to wait before the given retry, starting at 1.
+type Backoff func(retry int) time.Duration
+
+// FixedBackoff waits the same delay before every retry.
+func FixedBackoff(delay time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff doubles the delay before each retry, starting at initial and never exceeding max. A max of 0 means no limit.
+func ExponentialBackoff(initial, max time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		d := initial
+		for i := 1; i < retry; i++ {
+			d *= 2
+			if max > 0 && d >= max {
+				return max
+			}
+		}
+		return d
+	}
+}
+
+// RetryOnExitCodes returns a predicate for RetryStep that only retries commands that exited with one of the given codes.
+func RetryOnExitCodes(codes ...int) func(error) bool {
+	return func(err error) bool {
+		var execErr *ExecError
+		if !errors.As(err, &execErr) {
+			return false
+		}
+		for _, code := range codes {
+			if execErr.Result.ExitCode == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryStep runs another step again if it fails.
+type RetryStep struct {
+	Step    Step
+	Retries int
+	Backoff Backoff
+	RetryIf []func(error) bool
+}
+
+// Run runs the wrapped step until it succeeds, it has been retried the configured number of times, or its error does not satisfy any of the RetryIf predicates.
+func (s RetryStep) Run(ctx context.Context, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		for attempt := 1; ; attempt++ {
+			res := <-s.Step.Run(ctx, r)
+			if res.Error == nil || attempt > s.Retries || ctx.Err() != nil || !s.shouldRetry(res.Error) {
+				result <- res
+				return
+			}
+			var delay time.Duration
+			if s.Backoff != nil {
+				delay = s.Backoff(attempt)
+			}
+			messages.Info(messages.RetryingStep, colors.Warn, attempt, s.Retries+1, s.Step, colors.Clear, res.Error, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				result <- Result{nil, ctx.Err(), nil}
+				return
+			}
+		}
+	}()
+	return result
+}
+
+func (s RetryStep) shouldRetry(err error) bool {
+	if len(s.RetryIf) == 0 {
+		return true
+	}
+	for _, f := range s.RetryIf {
+		if f(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the step being retried.
+func (s RetryStep) Unwrap() Step {
+	return s.Step
+}
+
+// String returns the wrapped step's description along with its number of retries.
+func (s RetryStep) String() string {
+	return fmt.Sprintf("%v (retry %d)", s.Step, s.Retries)
+}
@@ -1,11 +1,27 @@
 package steps
 
+import "context"
+
 // RunStep handles Running a new task.
 type RunStep struct {
-	TaskName string
+	TaskName   string
+	InheritEnv bool // Whether the task starts with this task's environment rather than the process's.
 }
 
 // Run begins running a new task.
-func (s RunStep) Run(r Result) chan Result {
-	return r.Context.RunTask(s.TaskName)
+func (s RunStep) Run(ctx context.Context, r Result) chan Result {
+	return r.Context.RunTask(taskEnv(ctx, r, s.InheritEnv), s.TaskName)
+}
+
+// taskEnv returns a context for running tasks that start with either the current task's environment or the process's.
+func taskEnv(ctx context.Context, r Result, inherit bool) context.Context {
+	if inherit {
+		return WithEnv(ctx, r.Context.GetEnv())
+	}
+	return WithEnv(ctx, nil)
+}
+
+// String returns the name of the task that the step runs.
+func (s RunStep) String() string {
+	return "run " + s.TaskName
 }
@@ -0,0 +1,414 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kettek/gobl/pkg/shell"
+)
+
+// ShStep runs a shell script with a built-in interpreter, so that it behaves the same on every platform without depending on a system shell. Commands are run as ExecSteps and pipelines as PipeSteps. The builtins cd, echo, export, exit, true, and false are provided, and cd and variables only affect the rest of the script.
+type ShStep struct {
+	Source string
+	Script *shell.Script
+}
+
+// Run runs the script, returning the result of the last command run. As in a shell, a failing command only fails the script if it is the last command run, so "a; b" succeeds if b succeeds and "a || b" succeeds if either does. If Script is nil, Source is parsed first.
+func (s *ShStep) Run(ctx context.Context, pr Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		script := s.Script
+		if script == nil {
+			var err error
+			if script, err = shell.Parse(s.Source); err != nil {
+				result <- Result{nil, err, nil}
+				return
+			}
+		}
+		sh := &shInterpreter{
+			ctx:  ctx,
+			pr:   pr,
+			dir:  pr.Context.WorkingDirectory(),
+			vars: map[string]string{"?": "0"},
+		}
+		result <- sh.run(script)
+	}()
+	return result
+}
+
+// String returns "sh" followed by the script.
+func (s *ShStep) String() string {
+	return "sh " + s.Source
+}
+
+// shInterpreter holds the state of a running script.
+type shInterpreter struct {
+	ctx  context.Context
+	pr   Result
+	dir  string
+	vars map[string]string // Unexported shell variables.
+	env  []string          // Variables exported by the script.
+	exit bool
+}
+
+func (sh *shInterpreter) run(script *shell.Script) Result {
+	last := Result{nil, nil, nil}
+	status := 0
+	for _, item := range script.Items {
+		if (item.Op == "&&" && status != 0) || (item.Op == "||" && status == 0) {
+			continue
+		}
+		if sh.ctx.Err() != nil {
+			return Result{nil, sh.ctx.Err(), nil}
+		}
+		r := sh.pipeline(item.Pipeline)
+		status = 0
+		if r.Error != nil {
+			var execErr *ExecError
+			if !errors.As(r.Error, &execErr) {
+				code, ok := startFailureStatus(r.Error)
+				if !ok || sh.ctx.Err() != nil {
+					return r
+				}
+				// As in a shell, a command that cannot be started only sets the status.
+				fmt.Fprintln(os.Stderr, "sh:", r.Error)
+				execErr = &ExecError{Result: &ExecResult{ExitCode: code, Stderr: r.Error.Error() + "\n"}, Err: r.Error}
+				r = Result{nil, execErr, nil}
+			}
+			status = execErr.Result.ExitCode
+		}
+		sh.vars["?"] = strconv.Itoa(status)
+		last = r
+		if sh.exit {
+			break
+		}
+	}
+	return last
+}
+
+// context returns a Result whose Context has the script's working directory and exported variables.
+func (sh *shInterpreter) context() Result {
+	return Result{sh.pr.Result, nil, &shContext{Context: sh.pr.Context, dir: sh.dir, env: sh.env}}
+}
+
+func (sh *shInterpreter) pipeline(pl *shell.Pipeline) Result {
+	if len(pl.Commands) == 1 {
+		c := pl.Commands[0]
+		args, err := sh.expandArgs(c.Args)
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		if len(args) == 0 {
+			for _, a := range c.Assigns {
+				sh.assign(a.Name, sh.expand(a.Value))
+			}
+			return Result{nil, nil, nil}
+		}
+		if builtin, ok := shBuiltins[args[0]]; ok {
+			return builtin(sh, c, args)
+		}
+		step, err := sh.execStep(c, args)
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		return <-step.Run(sh.ctx, sh.context())
+	}
+
+	pipe := &PipeStep{}
+	for i, c := range pl.Commands {
+		args, err := sh.expandArgs(c.Args)
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		if len(args) == 0 {
+			return Result{nil, fmt.Errorf("assignments cannot be used in a pipeline"), nil}
+		}
+		// Echoing into a pipeline is common enough to support by writing the text to the next command's input.
+		if i == 0 && args[0] == "echo" && len(c.Redirects) == 0 {
+			pipe.Stdin = &Input{Text: echo(args[1:])}
+			continue
+		}
+		if _, ok := shBuiltins[args[0]]; ok {
+			return Result{nil, fmt.Errorf("%s cannot be used in a pipeline", args[0]), nil}
+		}
+		step, err := sh.execStep(c, args)
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		pipe.Commands = append(pipe.Commands, step)
+	}
+	return <-pipe.Run(sh.ctx, sh.context())
+}
+
+// execStep returns an ExecStep for the command with its assignments and redirects applied.
+func (sh *shInterpreter) execStep(c *shell.Command, args []string) (*ExecStep, error) {
+	s := &ExecStep{GracePeriod: DefaultGracePeriod}
+	// The arguments have already been expanded by the interpreter.
+	for _, a := range args {
+		s.Args = append(s.Args, Raw(a))
+	}
+	for _, a := range c.Assigns {
+		s.Env = append(s.Env, a.Name+"="+sh.expand(a.Value))
+	}
+	for _, r := range c.Redirects {
+		target := sh.expand(r.Target)
+		switch {
+		case r.Op == "<" && r.Fd == 0:
+			s.Stdin = &Input{Path: target}
+		case (r.Op == ">" || r.Op == ">>") && r.Fd == 1:
+			s.Stdout = &Output{Path: target, Append: r.Op == ">>"}
+		case (r.Op == ">" || r.Op == ">>") && r.Fd == 2:
+			s.Stderr = &Output{Path: target, Append: r.Op == ">>"}
+		case (r.Op == ">" || r.Op == ">>") && r.Fd == -1:
+			s.Stdout = &Output{Path: target, Append: r.Op == ">>"}
+			s.MergeStderr = true
+		case r.Op == ">&" && r.Fd == 2 && target == "1":
+			s.MergeStderr = true
+		case r.Op == ">&" && r.Fd == 1 && target == "2":
+			s.Stdout = &Output{Writer: os.Stderr}
+		default:
+			return nil, fmt.Errorf("unsupported redirect %d%s%s", r.Fd, r.Op, target)
+		}
+	}
+	return s, nil
+}
+
+// expandArgs expands the command's arguments, including any unquoted glob patterns that match files.
+func (sh *shInterpreter) expandArgs(words []shell.Word) ([]string, error) {
+	var args []string
+	for _, w := range words {
+		matches, err := sh.glob(w)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) > 0 {
+			args = append(args, matches...)
+		} else {
+			args = append(args, sh.expand(w))
+		}
+	}
+	return args, nil
+}
+
+// expand returns the word with its variables expanded. Unlike a shell, expanded variables are never split into multiple arguments.
+func (sh *shInterpreter) expand(w shell.Word) string {
+	var s strings.Builder
+	for _, p := range w {
+		if p.Var {
+			s.WriteString(sh.lookup(p.Text))
+		} else {
+			s.WriteString(p.Text)
+		}
+	}
+	return s.String()
+}
+
+// glob returns the files matched by the word if it contains an unquoted glob pattern.
+func (sh *shInterpreter) glob(w shell.Word) ([]string, error) {
+	var pattern strings.Builder
+	hasMeta := false
+	for _, p := range w {
+		text := p.Text
+		if p.Var {
+			text = sh.lookup(p.Text)
+		}
+		if !p.Var && !p.Quoted && strings.ContainsAny(text, "*?[") {
+			hasMeta = true
+			pattern.WriteString(text)
+		} else if runtime.GOOS == "windows" {
+			// Backslashes are path separators on windows, so meta characters cannot be escaped.
+			pattern.WriteString(text)
+		} else {
+			for _, c := range text {
+				if strings.ContainsRune("*?[\\", c) {
+					pattern.WriteRune('\\')
+				}
+				pattern.WriteRune(c)
+			}
+		}
+	}
+	if !hasMeta {
+		return nil, nil
+	}
+	p := pattern.String()
+	if filepath.IsAbs(p) {
+		return filepath.Glob(p)
+	}
+	matches, err := filepath.Glob(filepath.Join(sh.dir, p))
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range matches {
+		if matches[i], err = filepath.Rel(sh.dir, m); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// assign sets the named variable. Variables that are exported, whether by the script or by being in the task's environment, are updated in the environment of the commands that follow.
+func (sh *shInterpreter) assign(name, value string) {
+	_, exported := LookupEnv(sh.env, name)
+	if !exported {
+		_, exported = LookupEnv(sh.pr.Context.GetEnv(), name)
+	}
+	if exported {
+		delete(sh.vars, name)
+		sh.env = append(sh.env, name+"="+value)
+		return
+	}
+	sh.vars[name] = value
+}
+
+// lookup returns the value of the named variable, or an empty string if it is not set. Variables set by the script take precedence over those found by LookupVar.
+func (sh *shInterpreter) lookup(name string) string {
+	if v, ok := sh.vars[name]; ok {
+		return v
+	}
+	if v, ok := LookupEnv(sh.env, name); ok {
+		return v
+	}
+	v, _ := LookupVar(sh.pr, name)
+	return v
+}
+
+// shContext overrides a Context's working directory and environment for commands run by a script.
+type shContext struct {
+	Context
+	dir string
+	env []string
+}
+
+func (c *shContext) WorkingDirectory() string {
+	return c.dir
+}
+
+func (c *shContext) GetEnv() []string {
+	return append(c.Context.GetEnv(), c.env...)
+}
+
+// shBuiltins are commands run by the interpreter itself.
+var shBuiltins = map[string]func(sh *shInterpreter, c *shell.Command, args []string) Result{
+	"cd": func(sh *shInterpreter, c *shell.Command, args []string) Result {
+		if len(args) != 2 {
+			return shFailure(args, 1, "usage: cd dir")
+		}
+		dir := args[1]
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(sh.dir, dir)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return shFailure(args, 1, fmt.Sprintf("cd: %s: no such directory", args[1]))
+		}
+		sh.dir = dir
+		return Result{nil, nil, nil}
+	},
+	"echo": func(sh *shInterpreter, c *shell.Command, args []string) Result {
+		step, err := sh.execStep(c, args)
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		w, closeOutput, err := step.Stdout.open(sh.dir, os.Stdout)
+		if err != nil {
+			return Result{nil, err, nil}
+		}
+		defer closeOutput()
+		text := echo(args[1:])
+		if _, err := io.WriteString(w, text); err != nil {
+			return Result{nil, err, nil}
+		}
+		return Result{&ExecResult{Command: args[0], Args: args[1:], Stdout: text}, nil, nil}
+	},
+	"export": func(sh *shInterpreter, c *shell.Command, args []string) Result {
+		// Assignments before export only apply to the names it exports, not to the rest of the script.
+		prefix := make(map[string]string)
+		for _, a := range c.Assigns {
+			prefix[a.Name] = sh.expand(a.Value)
+		}
+		for _, a := range args[1:] {
+			name, value := a, ""
+			if i := strings.Index(a, "="); i != -1 {
+				name, value = a[:i], a[i+1:]
+			} else if v, ok := prefix[name]; ok {
+				value = v
+			} else {
+				value = sh.lookup(name)
+			}
+			if !shell.IsName(name) {
+				return shFailure(args, 1, fmt.Sprintf("export: %s: not a valid name", name))
+			}
+			delete(sh.vars, name)
+			sh.env = append(sh.env, name+"="+value)
+		}
+		return Result{nil, nil, nil}
+	},
+	"exit": func(sh *shInterpreter, c *shell.Command, args []string) Result {
+		sh.exit = true
+		code, _ := strconv.Atoi(sh.vars["?"])
+		if len(args) > 1 {
+			var err error
+			if code, err = strconv.Atoi(args[1]); err != nil {
+				return shFailure(args, 2, fmt.Sprintf("exit: %s: numeric argument required", args[1]))
+			}
+		}
+		if code != 0 {
+			return shFailure(args, code, "")
+		}
+		return Result{nil, nil, nil}
+	},
+	"true": func(sh *shInterpreter, c *shell.Command, args []string) Result {
+		return Result{&ExecResult{Command: args[0], Args: args[1:]}, nil, nil}
+	},
+	"false": func(sh *shInterpreter, c *shell.Command, args []string) Result {
+		return shFailure(args, 1, "")
+	},
+}
+
+// startFailureStatus returns the exit status that a shell gives a command that could not be run: 127 if it was not found, 126 if it could not be executed, and 1 if one of its redirects could not be opened.
+func startFailureStatus(err error) (int, bool) {
+	var execErr *exec.Error
+	if errors.As(err, &execErr) {
+		if errors.Is(execErr.Err, os.ErrPermission) {
+			return 126, true
+		}
+		return 127, true
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		switch {
+		case pathErr.Op == "open":
+			return 1, true
+		case errors.Is(pathErr, os.ErrPermission):
+			return 126, true
+		case errors.Is(pathErr, os.ErrNotExist):
+			return 127, true
+		}
+	}
+	return 0, false
+}
+
+// shFailure returns the failed result of a builtin, printing the message, if any, to stderr.
+func shFailure(args []string, code int, message string) Result {
+	if message != "" {
+		fmt.Fprintln(os.Stderr, message)
+		message += "\n"
+	}
+	r := &ExecResult{Command: args[0], Args: args[1:], ExitCode: code, Stderr: message}
+	return Result{nil, &ExecError{Result: r, Err: fmt.Errorf("exit status %d", code)}, nil}
+}
+
+// echo returns the text written by the echo builtin for the given arguments.
+func echo(args []string) string {
+	if len(args) > 0 && args[0] == "-n" {
+		return strings.Join(args[1:], " ")
+	}
+	return strings.Join(args, " ") + "\n"
+}
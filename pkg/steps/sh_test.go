@@ -0,0 +1,114 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kettek/gobl/pkg/shell"
+)
+
+// testContext is a minimal Context for running steps in tests.
+type testContext struct {
+	env  []string
+	vars map[string]string
+	dir  string
+}
+
+func (c *testContext) GetEnv() []string                  { return append([]string(nil), c.env...) }
+func (c *testContext) AddEnv(env ...string)              { c.env = append(c.env, env...) }
+func (c *testContext) UnsetEnv(...string)                {}
+func (c *testContext) ClearEnv(...string)                {}
+func (c *testContext) Reset([]string)                    {}
+func (c *testContext) WorkingDirectory() string          { return c.dir }
+func (c *testContext) SetWorkingDirectory(dir string)    { c.dir = dir }
+func (c *testContext) UpdateWorkingDirectory(dir string) { c.dir = dir }
+func (c *testContext) Var(name string) (string, bool) {
+	v, ok := c.vars[name]
+	return v, ok
+}
+func (c *testContext) SetVar(name, value string) {
+	if c.vars == nil {
+		c.vars = make(map[string]string)
+	}
+	c.vars[name] = value
+}
+func (c *testContext) RunTask(context.Context, string) chan Result {
+	return nil
+}
+
+// runSh runs the script with the PATH of the test process, returning its result and the output of its last command.
+func runSh(t *testing.T, env []string, src string) (Result, string) {
+	t.Helper()
+	script, err := shell.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &testContext{env: env, dir: t.TempDir()}
+	r := <-(&ShStep{Source: src, Script: script}).Run(context.Background(), Result{Result: nil, Error: nil, Context: ctx})
+	return r, ResultString(r.Result)
+}
+
+func TestShCommandNotFound(t *testing.T) {
+	r, out := runSh(t, nil, "gobl-missing-tool || echo fallback")
+	if r.Error != nil {
+		t.Fatal(r.Error)
+	}
+	if out != "fallback" {
+		t.Errorf("output = %q, want %q", out, "fallback")
+	}
+
+	r, out = runSh(t, nil, "gobl-missing-tool; echo $?")
+	if r.Error != nil {
+		t.Fatal(r.Error)
+	}
+	if out != "127" {
+		t.Errorf("status = %q, want %q", out, "127")
+	}
+
+	r, _ = runSh(t, nil, "gobl-missing-tool")
+	var execErr *ExecError
+	if !errors.As(r.Error, &execErr) || execErr.Result.ExitCode != 127 {
+		t.Errorf("expected an ExecError with status 127, got %v", r.Error)
+	}
+}
+
+func TestShAssignExported(t *testing.T) {
+	r, out := runSh(t, []string{"PATH=/usr/bin:/bin", "GOBL_TEST=old"}, `GOBL_TEST=new; export OTHER=a; OTHER=b; env`)
+	if r.Error != nil {
+		t.Skip("env is not available:", r.Error)
+	}
+	if !strings.Contains(out, "GOBL_TEST=new") || strings.Contains(out, "GOBL_TEST=old") {
+		t.Errorf("GOBL_TEST was not updated in the environment:\n%s", out)
+	}
+	if !strings.Contains(out, "OTHER=b") {
+		t.Errorf("OTHER was not updated in the environment:\n%s", out)
+	}
+}
+
+func TestShNilScript(t *testing.T) {
+	ctx := &testContext{dir: t.TempDir()}
+	r := <-(&ShStep{Source: "echo 'unterminated"}).Run(context.Background(), Result{Result: nil, Error: nil, Context: ctx})
+	if r.Error == nil {
+		t.Error("expected the parse error")
+	}
+}
+
+func TestShExportPrefixAssignments(t *testing.T) {
+	r, out := runSh(t, nil, `FOO=1 export BAR; echo "[$FOO]"`)
+	if r.Error != nil {
+		t.Fatal(r.Error)
+	}
+	if out != "[]" {
+		t.Errorf("prefix assignment of export persisted: %q", out)
+	}
+
+	r, out = runSh(t, nil, `FOO=1 export FOO; echo "[$FOO]"`)
+	if r.Error != nil {
+		t.Fatal(r.Error)
+	}
+	if out != "[1]" {
+		t.Errorf("exported prefix assignment = %q, want %q", out, "[1]")
+	}
+}
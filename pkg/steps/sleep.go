@@ -1,6 +1,9 @@
 package steps
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // SleepStep causes a delay.
 type SleepStep struct {
@@ -8,7 +11,7 @@ type SleepStep struct {
 }
 
 // Run sleeps for the given delay.
-func (s SleepStep) Run(pr Result) chan Result {
+func (s SleepStep) Run(ctx context.Context, pr Result) chan Result {
 	result := make(chan Result)
 
 	go func() {
@@ -17,9 +20,18 @@ func (s SleepStep) Run(pr Result) chan Result {
 			result <- Result{nil, err, nil}
 			return
 		}
-		time.Sleep(d)
-		result <- Result{s.Duration, nil, nil}
+		select {
+		case <-time.After(d):
+			result <- Result{s.Duration, nil, nil}
+		case <-ctx.Done():
+			result <- Result{nil, ctx.Err(), nil}
+		}
 	}()
 
 	return result
 }
+
+// String returns the duration that the step sleeps for.
+func (s SleepStep) String() string {
+	return "sleep " + s.Duration
+}
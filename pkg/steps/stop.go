@@ -0,0 +1,70 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+)
+
+// Stop describes how a process exited after being asked to stop.
+type Stop struct {
+	Command     string
+	Pid         int
+	Signal      os.Signal
+	GracePeriod time.Duration
+	Duration    time.Duration // How long the process took to exit.
+	Forced      bool          // If the process had to be killed.
+}
+
+// StopReport collects the Stops of processes stopped by a cancelled context.
+type StopReport struct {
+	lock  sync.Mutex
+	stops []Stop
+}
+
+type stopReportKey struct{}
+
+// WithStopReport returns a context that records stopped processes in the given report.
+func WithStopReport(ctx context.Context, r *StopReport) context.Context {
+	return context.WithValue(ctx, stopReportKey{}, r)
+}
+
+// StopReportFrom returns the context's StopReport, if any.
+func StopReportFrom(ctx context.Context) *StopReport {
+	r, _ := ctx.Value(stopReportKey{}).(*StopReport)
+	return r
+}
+
+func (r *StopReport) add(s Stop) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.stops = append(r.stops, s)
+}
+
+// Stops returns the recorded Stops.
+func (r *StopReport) Stops() []Stop {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Stop(nil), r.stops...)
+}
+
+// Print prints a summary of which processes exited cleanly and which had to be killed. Nothing is printed if no processes were stopped.
+func (r *StopReport) Print() {
+	stops := r.Stops()
+	if len(stops) == 0 {
+		return
+	}
+	fmt.Printf(messages.StoppedProcesses+"\n", colors.Notice, colors.Clear)
+	for _, s := range stops {
+		if s.Forced {
+			fmt.Printf(messages.KilledProcess+"\n", colors.Warn, s.Command, s.Pid, colors.Clear, s.Signal, s.GracePeriod)
+		} else {
+			fmt.Printf(messages.ExitedProcess+"\n", colors.Success, s.Command, s.Pid, colors.Clear, s.Signal, s.Duration.Round(time.Millisecond))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout can be compared against with errors.Is to check if a step or task was stopped because it ran out of time.
+var ErrTimeout = errors.New("timed out")
+
+// TimeoutError is returned when a step or task does not complete within its time limit.
+type TimeoutError struct {
+	Duration time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Duration)
+}
+
+// Is returns true if target is ErrTimeout.
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// TimeoutStep limits how long another step may run.
+type TimeoutStep struct {
+	Step     Step
+	Duration time.Duration
+}
+
+// Run runs the wrapped step, cancelling it and returning a TimeoutError if it does not complete in time.
+func (s TimeoutStep) Run(ctx context.Context, r Result) chan Result {
+	result := make(chan Result)
+	go func() {
+		timeoutCtx, cancel := context.WithTimeout(ctx, s.Duration)
+		defer cancel()
+		res := <-s.Step.Run(timeoutCtx, r)
+		if ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+			res.Error = &TimeoutError{Duration: s.Duration}
+		}
+		result <- res
+	}()
+	return result
+}
+
+// Unwrap returns the step being limited.
+func (s TimeoutStep) Unwrap() Step {
+	return s.Step
+}
+
+// String returns the wrapped step's description along with its time limit.
+func (s TimeoutStep) String() string {
+	return fmt.Sprintf("%v (timeout %s)", s.Step, s.Duration)
+}
@@ -1,13 +1,23 @@
 package steps
 
+import (
+	"context"
+	"strings"
+)
+
 // WatchStep handles setting up watch conditions.
 type WatchStep struct {
 	Paths []string
 }
 
 // Run does nothing.
-func (s WatchStep) Run(r Result) chan Result {
+func (s WatchStep) Run(ctx context.Context, r Result) chan Result {
 	result := make(chan Result)
 	result <- Result{}
 	return result
 }
+
+// String returns the paths that the step watches.
+func (s WatchStep) String() string {
+	return "watch " + strings.Join(s.Paths, " ")
+}
@@ -0,0 +1,408 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// block is a sequence of parsed steps.
+type block []node
+
+// node is a step or a block construct, such as a conditional, in a parsed block.
+type node interface {
+	run(ctx context.Context, g *Task, prev steps.Result) steps.Result
+}
+
+// stepNode is a single step.
+type stepNode struct {
+	step steps.Step
+}
+
+// catchNode is a step or block along with the Catch that handles its error.
+type catchNode struct {
+	node  node
+	catch steps.Step
+}
+
+// ifNode is a conditional block. The body of the first branch whose condition is true runs, or otherwise's if none are.
+type ifNode struct {
+	branches  []ifBranch
+	otherwise block
+}
+
+type ifBranch struct {
+	condition steps.Step
+	body      block
+}
+
+// promptNode is a prompt along with the blocks that run for each answer.
+type promptNode struct {
+	prompt steps.Step
+	yes    block
+	no     block
+}
+
+// forEachNode is a loop whose body runs once for each item of its ForEach step.
+type forEachNode struct {
+	loop steps.Step
+	body block
+}
+
+// runBlock runs each node of the block in order, stopping at the first error. The result of the last node is returned.
+func (g *Task) runBlock(ctx context.Context, b block, prev steps.Result) steps.Result {
+	for _, n := range b {
+		if ctx.Err() != nil {
+			return steps.Result{Result: nil, Error: ctx.Err(), Context: prev.Context}
+		}
+		result := n.run(ctx, g, prev)
+		if result.Error != nil {
+			return result
+		}
+		prev = result
+	}
+	return prev
+}
+
+// runStep runs a single step, returning its result with the previous result's context.
+func (g *Task) runStep(ctx context.Context, step steps.Step, prev steps.Result) steps.Result {
+	messages.Debug("\t%s▸ %v%s", colors.Info, step, colors.Clear)
+	result := <-step.Run(ctx, prev)
+	result.Context = prev.Context
+	if ctx.Err() != nil {
+		return steps.Result{Result: result.Result, Error: ctx.Err(), Context: prev.Context}
+	}
+	return result
+}
+
+func (n *stepNode) run(ctx context.Context, g *Task, prev steps.Result) steps.Result {
+	return g.runStep(ctx, n.step, prev)
+}
+
+func (n *catchNode) run(ctx context.Context, g *Task, prev steps.Result) steps.Result {
+	result := n.node.run(ctx, g, prev)
+	if result.Error != nil && ctx.Err() == nil {
+		if catchResult := <-n.catch.Run(ctx, result); catchResult.Error != nil {
+			catchResult.Context = prev.Context
+			return catchResult
+		}
+		// The error was handled, so it should not fail the task.
+		result.Error = nil
+	}
+	return result
+}
+
+func (n *ifNode) run(ctx context.Context, g *Task, prev steps.Result) steps.Result {
+	for _, b := range n.branches {
+		result := g.runStep(ctx, b.condition, prev)
+		if result.Error != nil {
+			return result
+		}
+		if result.Result == true {
+			return g.runBlock(ctx, b.body, prev)
+		}
+	}
+	return g.runBlock(ctx, n.otherwise, prev)
+}
+
+func (n *promptNode) run(ctx context.Context, g *Task, prev steps.Result) steps.Result {
+	result := g.runStep(ctx, n.prompt, prev)
+	if result.Error != nil {
+		return result
+	}
+	if result.Result == true {
+		return g.runBlock(ctx, n.yes, prev)
+	}
+	return g.runBlock(ctx, n.no, prev)
+}
+
+func (n *forEachNode) run(ctx context.Context, g *Task, prev steps.Result) steps.Result {
+	result := g.runStep(ctx, n.loop, prev)
+	if result.Error != nil {
+		return result
+	}
+	items, _ := result.Result.([]string)
+	loop := steps.Unwrap(n.loop).(steps.ForEachStep)
+	limit := 1
+	if loop.Parallel {
+		limit = loop.Limit
+		if limit <= 0 || limit > len(items) {
+			limit = len(items)
+		}
+	}
+	if err := g.runIterations(ctx, n.body, loop.Var, items, limit, prev); err != nil {
+		return steps.Result{Result: nil, Error: err, Context: prev.Context}
+	}
+	// The loop passes on the result of the step before it.
+	return prev
+}
+
+// runIterations runs the body once for each item, with at most limit iterations running at a time. Each iteration gets its own copy of the context with the named variable set to its item. Once an iteration fails, no more are started and those running are cancelled.
+func (g *Task) runIterations(ctx context.Context, body block, name string, items []string, limit int, prev steps.Result) error {
+	errs := runLimited(ctx, len(items), limit, true, func(ctx context.Context, i int) error {
+		messages.Debug("\t%s▸ %s=%s%s", colors.Info, name, items[i], colors.Clear)
+		c := cloneContext(prev.Context)
+		c.SetVar(name, items[i])
+		return g.runBlock(ctx, body, steps.Result{Result: prev.Result, Error: nil, Context: c}).Error
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var failed []int
+	for i, err := range errs {
+		// Skip iterations that were only cancelled because another failed.
+		if err != nil && !errors.Is(err, context.Canceled) {
+			failed = append(failed, i)
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%s=%s: %w", name, items[failed[0]], errs[failed[0]])
+	}
+	var errStrings []string
+	for _, i := range failed {
+		errStrings = append(errStrings, fmt.Sprintf("%s=%s -> %s", name, items[i], errs[i]))
+	}
+	return errors.New(strings.Join(errStrings, ", "))
+}
+
+// cloneContext returns a copy of the Context for a loop iteration or matrix combination, so that the steps it runs do not affect the others. Contexts that cannot be copied, which is only possible for Contexts other than gobl's, are shared apart from their variables.
+func cloneContext(c steps.Context) steps.Context {
+	if c, ok := c.(interface{ Clone() steps.Context }); ok {
+		return c.Clone()
+	}
+	return &varsContext{Context: c, vars: make(map[string]string)}
+}
+
+// varsContext overrides a Context's variables.
+type varsContext struct {
+	steps.Context
+	vars map[string]string
+}
+
+func (c *varsContext) Var(name string) (string, bool) {
+	if v, ok := c.vars[name]; ok {
+		return v, true
+	}
+	return c.Context.Var(name)
+}
+
+func (c *varsContext) SetVar(name, value string) {
+	c.vars[name] = value
+}
+
+// runLimited calls run for each index from 0 to n, with at most limit calls running at a time, returning the error of each. If failFast is set, once a call fails no more are started and the context passed to those running is cancelled. Calls that are never started have the context's error.
+func runLimited(ctx context.Context, n, limit int, failFast bool, run func(ctx context.Context, i int) error) []error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-runCtx.Done():
+		}
+		if runCtx.Err() != nil {
+			for ; i < n; i++ {
+				errs[i] = runCtx.Err()
+			}
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if errs[i] = run(runCtx, i); errs[i] != nil && failFast {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+// parseSteps parses a list of steps into a block, matching block constructs such as If and Prompt with the steps that end them. Errors identify the offending step by its position in the list.
+func parseSteps(list []steps.Step) (block, error) {
+	p := &stepParser{steps: list}
+	b, end, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, p.errorf(p.pos, "no matching %s", openerOf(end))
+	}
+	return b, nil
+}
+
+type stepParser struct {
+	steps []steps.Step
+	pos   int
+}
+
+// errorf returns an error for the step at index i.
+func (p *stepParser) errorf(i int, format string, args ...interface{}) error {
+	return fmt.Errorf("step %d (%v): %s", i+1, p.steps[i], fmt.Sprintf(format, args...))
+}
+
+// block parses steps until the end of the list or a step that ends or divides a block, such as Else or End, which is returned without being consumed.
+func (p *stepParser) block() (block, steps.Step, error) {
+	var b block
+	for p.pos < len(p.steps) {
+		step := p.steps[p.pos]
+		start := p.pos
+		p.pos++
+		var n node
+		switch steps.Unwrap(step).(type) {
+		case steps.YesStep, steps.NoStep, steps.EndStep, steps.ElseIfStep, steps.ElseStep, steps.EndIfStep, steps.EndForEachStep:
+			p.pos--
+			return b, step, nil
+		case steps.CatchStep:
+			if len(b) == 0 {
+				return nil, nil, p.errorf(start, "must follow a step or block")
+			}
+			if _, ok := b[len(b)-1].(*catchNode); ok {
+				return nil, nil, p.errorf(start, "the preceding step already has a catch")
+			}
+			b[len(b)-1] = &catchNode{node: b[len(b)-1], catch: step}
+			continue
+		case steps.IfStep:
+			var err error
+			if n, err = p.ifBlock(start); err != nil {
+				return nil, nil, err
+			}
+		case steps.PromptStep:
+			var err error
+			if n, err = p.promptBlock(start); err != nil {
+				return nil, nil, err
+			}
+		case steps.ForEachStep:
+			var err error
+			if n, err = p.forEachBlock(start); err != nil {
+				return nil, nil, err
+			}
+		default:
+			n = &stepNode{step: step}
+		}
+		b = append(b, n)
+	}
+	return b, nil, nil
+}
+
+// ifBlock parses the block of the If step at index start.
+func (p *stepParser) ifBlock(start int) (*ifNode, error) {
+	n := &ifNode{}
+	condition := p.steps[start]
+	for {
+		body, end, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		if end == nil {
+			return nil, p.errorf(start, "no matching endif")
+		}
+		switch steps.Unwrap(end).(type) {
+		case steps.ElseIfStep, steps.ElseStep:
+			if condition == nil {
+				return nil, p.errorf(p.pos, "after else")
+			}
+			n.branches = append(n.branches, ifBranch{condition: condition, body: body})
+			condition = nil
+			if _, ok := steps.Unwrap(end).(steps.ElseIfStep); ok {
+				condition = end
+			}
+		case steps.EndIfStep:
+			if condition == nil {
+				n.otherwise = body
+			} else {
+				n.branches = append(n.branches, ifBranch{condition: condition, body: body})
+			}
+			p.pos++
+			return n, nil
+		default:
+			return nil, p.errorf(p.pos, "inside an if block, which must be ended with endif first")
+		}
+		p.pos++
+	}
+}
+
+// promptBlock parses the block of the Prompt step at index start.
+func (p *stepParser) promptBlock(start int) (*promptNode, error) {
+	n := &promptNode{prompt: p.steps[start]}
+	body, end, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > 0 {
+		return nil, p.errorf(start, "must be followed by yes, no, or end")
+	}
+	for {
+		if end == nil {
+			return nil, p.errorf(start, "no matching end")
+		}
+		switch steps.Unwrap(end).(type) {
+		case steps.YesStep:
+			if n.yes != nil {
+				return nil, p.errorf(p.pos, "the prompt already has a yes block")
+			}
+			p.pos++
+			n.yes, end, err = p.block()
+			if n.yes == nil {
+				n.yes = block{}
+			}
+		case steps.NoStep:
+			if n.no != nil {
+				return nil, p.errorf(p.pos, "the prompt already has a no block")
+			}
+			p.pos++
+			n.no, end, err = p.block()
+			if n.no == nil {
+				n.no = block{}
+			}
+		case steps.EndStep:
+			p.pos++
+			return n, nil
+		default:
+			return nil, p.errorf(p.pos, "inside a prompt block, which must be ended with end first")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// forEachBlock parses the block of the ForEach step at index start.
+func (p *stepParser) forEachBlock(start int) (*forEachNode, error) {
+	body, end, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if end == nil {
+		return nil, p.errorf(start, "no matching endforeach")
+	}
+	if _, ok := steps.Unwrap(end).(steps.EndForEachStep); !ok {
+		return nil, p.errorf(p.pos, "inside a foreach block, which must be ended with endforeach first")
+	}
+	p.pos++
+	return &forEachNode{loop: p.steps[start], body: body}, nil
+}
+
+// openerOf returns the name of the step that begins the block that the given step belongs to.
+func openerOf(step steps.Step) string {
+	switch steps.Unwrap(step).(type) {
+	case steps.YesStep, steps.NoStep, steps.EndStep:
+		return "prompt"
+	case steps.EndForEachStep:
+		return "foreach"
+	}
+	return "if"
+}
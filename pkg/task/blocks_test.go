@@ -0,0 +1,173 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// errorStep is a step that fails with its error.
+type errorStep struct {
+	err error
+}
+
+func (s errorStep) Run(ctx context.Context, r steps.Result) chan steps.Result {
+	result := make(chan steps.Result, 1)
+	result <- steps.Result{Result: nil, Error: s.err, Context: nil}
+	return result
+}
+
+// namedStep is a step that does nothing, identified by its name in parsed blocks.
+type namedStep string
+
+func (s namedStep) Run(ctx context.Context, r steps.Result) chan steps.Result {
+	result := make(chan steps.Result, 1)
+	result <- steps.Result{}
+	return result
+}
+
+func (s namedStep) String() string {
+	return string(s)
+}
+
+// describe returns a compact description of a parsed block, such as "a if(c: [b] else: [d])".
+func describe(b block) string {
+	var parts []string
+	for _, n := range b {
+		switch n := n.(type) {
+		case *stepNode:
+			parts = append(parts, fmt.Sprint(n.step))
+		case *catchNode:
+			parts = append(parts, "catch("+describe(block{n.node})+")")
+		case *ifNode:
+			var branches []string
+			for _, branch := range n.branches {
+				branches = append(branches, strings.TrimPrefix(strings.TrimPrefix(fmt.Sprint(branch.condition), "else "), "if ")+": ["+describe(branch.body)+"]")
+			}
+			if n.otherwise != nil {
+				branches = append(branches, "else: ["+describe(n.otherwise)+"]")
+			}
+			parts = append(parts, "if("+strings.Join(branches, " ")+")")
+		case *promptNode:
+			var answers []string
+			if n.yes != nil {
+				answers = append(answers, "yes: ["+describe(n.yes)+"]")
+			}
+			if n.no != nil {
+				answers = append(answers, "no: ["+describe(n.no)+"]")
+			}
+			parts = append(parts, "prompt("+strings.Join(answers, " ")+")")
+		case *forEachNode:
+			parts = append(parts, "foreach(["+describe(n.body)+"])")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParseSteps(t *testing.T) {
+	var (
+		a, b, c = namedStep("a"), namedStep("b"), namedStep("c")
+		prompt  = steps.PromptStep{Message: "p"}
+		yes     = steps.YesStep{}
+		no      = steps.NoStep{}
+		end     = steps.EndStep{}
+		ifStep  = steps.IfStep{Description: "x"}
+		elseIf  = steps.ElseIfStep{Description: "y"}
+		elseStp = steps.ElseStep{}
+		endIf   = steps.EndIfStep{}
+		forEach = steps.ForEachStep{Description: "items"}
+		endEach = steps.EndForEachStep{}
+		catch   = steps.CatchStep{}
+	)
+	tests := []struct {
+		name  string
+		steps []steps.Step
+		want  string
+		err   string
+	}{
+		{"steps", []steps.Step{a, b}, "a b", ""},
+		{"prompt", []steps.Step{prompt, yes, a, no, b, end, c}, "prompt(yes: [a] no: [b]) c", ""},
+		{"prompt no first", []steps.Step{prompt, no, a, yes, b, end}, "prompt(yes: [b] no: [a])", ""},
+		{"empty prompt", []steps.Step{prompt, end}, "prompt()", ""},
+		{"empty answer", []steps.Step{prompt, yes, end}, "prompt(yes: [])", ""},
+		{"nested prompts", []steps.Step{prompt, yes, prompt, yes, a, end, b, no, c, end}, "prompt(yes: [prompt(yes: [a]) b] no: [c])", ""},
+		{"nested prompt in no", []steps.Step{prompt, no, prompt, no, a, end, end, b}, "prompt(no: [prompt(no: [a])]) b", ""},
+		{"if", []steps.Step{ifStep, a, endIf}, "if(x: [a])", ""},
+		{"if else", []steps.Step{ifStep, a, elseIf, b, elseStp, c, endIf}, "if(x: [a] y: [b] else: [c])", ""},
+		{"nested if", []steps.Step{ifStep, ifStep, a, endIf, elseStp, b, endIf}, "if(x: [if(x: [a])] else: [b])", ""},
+		{"foreach", []steps.Step{forEach, a, forEach, b, endEach, endEach}, "foreach([a foreach([b])])", ""},
+		{"mixed", []steps.Step{forEach, ifStep, prompt, yes, a, end, endIf, endEach}, "foreach([if(x: [prompt(yes: [a])])])", ""},
+		{"catch", []steps.Step{a, catch, b}, "catch(a) b", ""},
+		{"catch block", []steps.Step{ifStep, a, catch, endIf, catch}, "catch(if(x: [catch(a)]))", ""},
+		{"catch prompt", []steps.Step{prompt, yes, a, end, catch}, "catch(prompt(yes: [a]))", ""},
+
+		{"unopened end", []steps.Step{a, end}, "", "step 2 (end): no matching prompt"},
+		{"unopened yes", []steps.Step{yes, a}, "", "step 1 (yes): no matching prompt"},
+		{"unopened no", []steps.Step{no}, "", "step 1 (no): no matching prompt"},
+		{"unopened endif", []steps.Step{endIf}, "", "step 1 (endif): no matching if"},
+		{"unopened else", []steps.Step{a, elseStp}, "", "step 2 (else): no matching if"},
+		{"unopened else if", []steps.Step{elseIf}, "", "step 1 (else if y): no matching if"},
+		{"unopened endforeach", []steps.Step{endEach}, "", "step 1 (endforeach): no matching foreach"},
+		{"unclosed prompt", []steps.Step{prompt, yes, a}, "", `step 1 (prompt "p"): no matching end`},
+		{"unclosed nested prompt", []steps.Step{prompt, yes, prompt, yes, a, end}, "", `step 1 (prompt "p"): no matching end`},
+		{"unclosed if", []steps.Step{ifStep, a}, "", "step 1 (if x): no matching endif"},
+		{"unclosed foreach", []steps.Step{forEach, a}, "", "step 1 (foreach items (as )): no matching endforeach"},
+		{"prompt body", []steps.Step{prompt, a, end}, "", `step 1 (prompt "p"): must be followed by yes, no, or end`},
+		{"second yes", []steps.Step{prompt, yes, a, yes, b, end}, "", "step 4 (yes): the prompt already has a yes block"},
+		{"second no", []steps.Step{prompt, no, a, no, end}, "", "step 4 (no): the prompt already has a no block"},
+		{"else after else", []steps.Step{ifStep, elseStp, a, elseStp, endIf}, "", "step 4 (else): after else"},
+		{"else if after else", []steps.Step{ifStep, elseStp, elseIf, endIf}, "", "step 3 (else if y): after else"},
+		{"endif in prompt", []steps.Step{ifStep, prompt, yes, endIf, end}, "", "step 4 (endif): inside a prompt block, which must be ended with end first"},
+		{"end in if", []steps.Step{prompt, yes, ifStep, end, endIf}, "", "step 4 (end): inside an if block, which must be ended with endif first"},
+		{"end in foreach", []steps.Step{prompt, yes, forEach, end}, "", "step 4 (end): inside a foreach block, which must be ended with endforeach first"},
+		{"endforeach in if", []steps.Step{forEach, ifStep, endEach}, "", "step 3 (endforeach): inside an if block, which must be ended with endif first"},
+		{"leading catch", []steps.Step{catch, a}, "", "step 1 (catch): must follow a step or block"},
+		{"catch first in block", []steps.Step{ifStep, catch, endIf}, "", "step 2 (catch): must follow a step or block"},
+		{"second catch", []steps.Step{a, catch, catch}, "", "step 3 (catch): the preceding step already has a catch"},
+	}
+	for _, tt := range tests {
+		b, err := parseSteps(tt.steps)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := describe(b); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCatchHandlesLastStep(t *testing.T) {
+	chdir(t)
+	failure := errors.New("failure")
+	var caught error
+	g := NewTask("catch", &testContext{})
+	g.steps = append(g.steps, errorStep{failure})
+	g.Catch(func(err error) error {
+		caught = err
+		return nil
+	})
+	if r := g.runSteps(context.Background()); r.Error != nil {
+		t.Errorf("handled error failed the task: %v", r.Error)
+	}
+	if !errors.Is(caught, failure) {
+		t.Errorf("Catch received %v, want %v", caught, failure)
+	}
+
+	g = NewTask("rethrow", &testContext{})
+	g.steps = append(g.steps, errorStep{failure})
+	g.Catch(func(err error) error { return err })
+	if r := g.runSteps(context.Background()); !errors.Is(r.Error, failure) {
+		t.Errorf("rethrown error = %v, want %v", r.Error, failure)
+	}
+}
@@ -0,0 +1,190 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kettek/gobl/pkg/cache"
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// CacheURLEnv is the environment variable used to configure a remote cache URL.
+const CacheURLEnv = "GOBL_CACHE_URL"
+
+// CacheEnv lists the environment variables that are included in cache keys in addition to those set by a task's steps. Other variables, such as PWD or TERM, differ between shells and machines without affecting a task's outputs.
+var CacheEnv = []string{"GOOS", "GOARCH", "GOARM", "GOAMD64", "GO386", "GOEXPERIMENT", "GOFLAGS", "CGO_ENABLED", "CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_CXXFLAGS", "CGO_LDFLAGS", "CC", "CXX"}
+
+// referencePattern matches the variable references of step arguments and Sh scripts, such as "${NAME}", "{{.NAME}}", and "$NAME".
+var referencePattern = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)|\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// OutputCache holds the outputs of tasks keyed by their inputs, environment, and steps.
+var OutputCache = newOutputCache()
+
+func newOutputCache() *cache.Cache {
+	c := cache.New(filepath.Join(".gobl", "cache"))
+	if url := os.Getenv(CacheURLEnv); url != "" {
+		c.Remote = cache.NewRemote(url)
+	}
+	return c
+}
+
+// cacheKey returns a key that identifies the task's outputs. It is built from the task's input file contents, parameters, the variables of its environment listed in CacheEnv, and its steps, including their resolved arguments, the values of the variables they reference, loop items, and conditions.
+func (g *Task) cacheKey() (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "task %q\n", g.Name)
+
+	inputs, err := globFiles(g.inputs)
+	if err != nil {
+		return "", err
+	}
+	hashes, err := hashFiles(inputs)
+	if err != nil {
+		return "", err
+	}
+	for _, input := range inputs {
+		fmt.Fprintf(h, "input %q %s\n", filepath.ToSlash(input), hashes[input])
+	}
+	for _, output := range g.outputs {
+		fmt.Fprintf(h, "output %q\n", output)
+	}
+
+	// Parameters may be referenced by steps' arguments, so their values are included.
+	for _, p := range g.params {
+		fmt.Fprintf(h, "param %q %q\n", p.Name, fmt.Sprint(dereference(p.Value)))
+	}
+
+	// Each matrix combination's values are set in its environment.
+	if g.matrix != nil {
+		for _, c := range g.matrix.combinations() {
+			fmt.Fprintf(h, "matrix %q\n", c)
+		}
+	}
+
+	env := g.context.GetEnv()
+	names := append([]string(nil), CacheEnv...)
+	sort.Strings(names)
+	for _, name := range names {
+		for _, e := range env {
+			if strings.HasPrefix(e, name+"=") {
+				fmt.Fprintf(h, "env %q\n", e)
+			}
+		}
+	}
+
+	// Files read by steps are resolved the same way the steps resolve them, following any changes of working directory.
+	pr := steps.Result{Result: nil, Error: nil, Context: g.context}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	// Variables referenced by steps may be set outside of the task, such as in the process's environment, so their values are included.
+	refs := make(map[string]bool)
+	addRefs := func(args ...string) {
+		for _, arg := range args {
+			for _, m := range referencePattern.FindAllStringSubmatch(arg, -1) {
+				refs[m[1]+m[2]] = true
+			}
+		}
+	}
+	resolve := func(args []string) []string {
+		addRefs(args...)
+		for i, arg := range args {
+			args[i] = steps.InterpolateDefined(arg, pr)
+		}
+		return args
+	}
+	for _, step := range g.steps {
+		switch step := steps.Unwrap(step).(type) {
+		case *steps.ExecStep:
+			fmt.Fprintf(h, "exec %q\n", resolve(step.Command()))
+		case *steps.PipeStep:
+			for _, c := range step.Commands {
+				fmt.Fprintf(h, "pipe %q\n", resolve(c.Command()))
+			}
+		case *steps.ShStep:
+			addRefs(step.Source)
+			fmt.Fprintf(h, "sh %q\n", step.Source)
+		case steps.EnvStep:
+			fmt.Fprintf(h, "env %q\n", resolve(append([]string(nil), step.Args...)))
+		case steps.ForEachStep:
+			addRefs(step.Description)
+			items, err := step.Items(pr)
+			fmt.Fprintf(h, "foreach %q %q %q %v\n", step.Var, step.Description, items, err)
+		case steps.IfStep:
+			ok, err := step.Condition(pr)
+			fmt.Fprintf(h, "if %q %v %v\n", step.Description, ok, err)
+		case steps.ElseIfStep:
+			ok, err := step.Condition(pr)
+			fmt.Fprintf(h, "elseif %q %v %v\n", step.Description, ok, err)
+		case steps.UnenvStep:
+			fmt.Fprintf(h, "unenv %q\n", step.Names)
+		case steps.ClearEnvStep:
+			fmt.Fprintf(h, "clearenv %q\n", step.Keep)
+		case steps.EnvFileStep:
+			addRefs(step.Paths...)
+			paths, err := step.Files(pr, wd)
+			if err != nil {
+				return "", err
+			}
+			for i, path := range paths {
+				data, err := os.ReadFile(path)
+				if err != nil && !os.IsNotExist(err) {
+					return "", err
+				}
+				fmt.Fprintf(h, "envfile %q %x\n", step.Paths[i], sha256.Sum256(data))
+			}
+		case steps.ChdirStep:
+			addRefs(step.Path)
+			path, err := steps.Interpolate(step.Path, pr)
+			if err != nil {
+				return "", err
+			}
+			wd = filepath.Join(wd, path)
+			fmt.Fprintf(h, "chdir %q\n", step.Path)
+		}
+	}
+	var vars []string
+	for name := range refs {
+		vars = append(vars, name)
+	}
+	sort.Strings(vars)
+	for _, name := range vars {
+		v, ok := steps.LookupVar(pr, name)
+		fmt.Fprintf(h, "var %q %v %q\n", name, ok, v)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// restoreOutputs restores the task's outputs from the OutputCache, returning true if they were found.
+func (g *Task) restoreOutputs(key string) bool {
+	hit, err := OutputCache.Restore(key)
+	if err != nil {
+		fmt.Printf(messages.CacheError+"\n", g.Name, err)
+		return false
+	}
+	if hit {
+		messages.Info(messages.CacheHit, colors.Info, g.Name, colors.Clear)
+	} else {
+		messages.Info(messages.CacheMiss, colors.Info, g.Name, colors.Clear)
+	}
+	return hit
+}
+
+// saveOutputs stores the task's outputs in the OutputCache.
+func (g *Task) saveOutputs(key string) {
+	outputs, err := globFiles(g.outputs)
+	if err == nil {
+		err = OutputCache.Save(key, outputs)
+	}
+	if err != nil {
+		fmt.Printf(messages.CacheError+"\n", g.Name, err)
+	}
+}
@@ -0,0 +1,145 @@
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// testContext is a minimal Context for tasks in tests.
+type testContext struct {
+	env  []string
+	vars map[string]string
+	dir  string
+}
+
+func (c *testContext) GetEnv() []string                                  { return append([]string(nil), c.env...) }
+func (c *testContext) AddEnv(env ...string)                              { c.env = append(c.env, env...) }
+func (c *testContext) UnsetEnv(...string)                                {}
+func (c *testContext) ClearEnv(...string)                                {}
+func (c *testContext) Reset(env []string)                                { c.env = env }
+func (c *testContext) WorkingDirectory() string                          { return c.dir }
+func (c *testContext) SetWorkingDirectory(dir string)                    { c.dir = dir }
+func (c *testContext) UpdateWorkingDirectory(dir string)                 { c.dir = dir }
+func (c *testContext) RunTask(context.Context, string) chan steps.Result { return nil }
+func (c *testContext) Var(name string) (string, bool) {
+	v, ok := c.vars[name]
+	return v, ok
+}
+func (c *testContext) SetVar(name, value string) {
+	if c.vars == nil {
+		c.vars = make(map[string]string)
+	}
+	c.vars[name] = value
+}
+
+// chdir changes to a new temporary directory for the duration of the test.
+func chdir(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestCacheKeyEnvFileFollowsChdir(t *testing.T) {
+	chdir(t)
+	if err := os.Mkdir("sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	g := NewTask("build", &testContext{vars: map[string]string{"NAME": "sub"}}).Chdir("${NAME}").EnvFile(".env")
+
+	key := func() string {
+		k, err := g.cacheKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	missing := key()
+	if err := os.WriteFile(filepath.Join("sub", ".env"), []byte("A=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := key()
+	if first == missing {
+		t.Error("key did not change when the env file read by the step was created")
+	}
+	if err := os.WriteFile(filepath.Join("sub", ".env"), []byte("A=2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if key() == first {
+		t.Error("key did not change when the env file read by the step changed")
+	}
+	// A file in the task's starting directory is not the one the step reads.
+	if err := os.WriteFile(".env", []byte("A=3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second := key()
+	if err := os.WriteFile(".env", []byte("A=4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if key() != second {
+		t.Error("key changed with an env file the step does not read")
+	}
+}
+
+func TestCacheKeyEnv(t *testing.T) {
+	chdir(t)
+	key := func(env ...string) string {
+		k, err := NewTask("build", &testContext{env: env}).cacheKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	base := key("GOOS=linux", "PWD=/a", "SHLVL=1")
+	if key("GOOS=linux", "PWD=/b", "SHLVL=2", "TERM=xterm") != base {
+		t.Error("key changed with variables that are not in CacheEnv")
+	}
+	if key("GOOS=windows", "PWD=/a", "SHLVL=1") == base {
+		t.Error("key did not change with GOOS")
+	}
+	if key("GOOS=linux", "GOOS_EXTRA=1") != key("GOOS=linux") {
+		t.Error("key changed with a variable whose name starts with a CacheEnv name")
+	}
+}
+
+func TestCacheKeyReferences(t *testing.T) {
+	chdir(t)
+	tests := []struct {
+		name  string
+		task  func(g *Task) *Task
+		env   []string
+		other []string
+	}{
+		{"exec", func(g *Task) *Task { return g.Exec("sh", "-c", "cat in.txt > out.txt; echo built ${NAME}") }, []string{"NAME=a"}, []string{"NAME=b"}},
+		{"template", func(g *Task) *Task { return g.Exec("echo", "{{.NAME}}") }, []string{"NAME=a"}, []string{"NAME=b"}},
+		{"sh", func(g *Task) *Task { return g.Sh("echo $NAME") }, []string{"NAME=a"}, []string{"NAME=b"}},
+		{"foreach", func(g *Task) *Task { return g.ForEach("${NAME}").Exec("echo", "${ITEM}").EndForEach() }, []string{"NAME=a"}, []string{"NAME=b"}},
+		{"ifenv", func(g *Task) *Task { return g.IfEnv("CI").Exec("echo", "ci").EndIf() }, nil, []string{"CI=1"}},
+		{"unset", func(g *Task) *Task { return g.Exec("echo", "${NAME}") }, nil, []string{"NAME="}},
+	}
+	for _, tt := range tests {
+		key := func(env []string) string {
+			k, err := tt.task(NewTask("build", &testContext{env: env})).cacheKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return k
+		}
+		if key(tt.env) == key(tt.other) {
+			t.Errorf("%s: key did not change with %q", tt.name, tt.other)
+		}
+		if key(tt.env) != key(append(tt.env, "OTHER=1")) {
+			t.Errorf("%s: key changed with a variable that is not referenced", tt.name)
+		}
+	}
+}
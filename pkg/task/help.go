@@ -0,0 +1,44 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kettek/gobl/pkg/colors"
+)
+
+// WriteHelp writes a description of the task, its dependencies, and its steps.
+func (g *Task) WriteHelp(w io.Writer) {
+	fmt.Fprintf(w, "%s%s%s\n", colors.Info, g.Name, colors.Clear)
+	if g.description != "" {
+		fmt.Fprintf(w, "  %s\n", g.description)
+	}
+	if g.group != "" {
+		fmt.Fprintf(w, "  group:      %s\n", g.group)
+	}
+	if len(g.dependencies) > 0 {
+		fmt.Fprintf(w, "  depends on: %s\n", strings.Join(g.dependencies, ", "))
+	}
+	if len(g.params) > 0 {
+		fmt.Fprintf(w, "  params:\n")
+		for _, p := range g.params {
+			fmt.Fprintf(w, "    %s=%s (%s)\n", p.Name, p.Default, p.Type())
+		}
+	}
+	if len(g.inputs) > 0 {
+		fmt.Fprintf(w, "  inputs:     %s\n", strings.Join(g.inputs, " "))
+	}
+	if len(g.outputs) > 0 {
+		fmt.Fprintf(w, "  outputs:    %s\n", strings.Join(g.outputs, " "))
+	}
+	if len(g.watchPaths) > 0 {
+		fmt.Fprintf(w, "  watches:    %s\n", strings.Join(g.watchPaths, " "))
+	}
+	if len(g.steps) > 0 {
+		fmt.Fprintf(w, "  steps:\n")
+		for _, step := range g.steps {
+			fmt.Fprintf(w, "    %v\n", step)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package task
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// Force causes tasks to run even if their outputs are up to date.
+var Force bool
+
+// StateFile is where the input and output hashes of tasks are recorded between runs.
+var StateFile = filepath.Join(".gobl", "state.json")
+
+var stateLock sync.Mutex
+
+// taskState is the recorded state of a task's inputs and outputs after its last successful run.
+type taskState struct {
+	Inputs  map[string]string `json:"inputs"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+// run runs the task's steps unless its outputs are up to date with its inputs.
+func (g *Task) run(ctx context.Context) steps.Result {
+	// Each run starts from a clean environment so that changes made by a previous run do not carry over.
+	g.context.Reset(steps.EnvFrom(ctx))
+	g.setParamVars()
+	if len(g.outputs) == 0 {
+		return g.runSteps(ctx)
+	}
+	if !Force {
+		upToDate, err := g.isUpToDate()
+		if err != nil {
+			fmt.Println(err)
+		} else if upToDate {
+			messages.Info(messages.UpToDateTask, colors.Info, g.Name, colors.Clear)
+			return steps.Result{Result: nil, Error: nil, Context: g.context}
+		}
+	}
+	key, err := g.cacheKey()
+	if err != nil {
+		fmt.Println(err)
+	} else if !Force && g.restoreOutputs(key) {
+		if err := g.recordState(); err != nil {
+			fmt.Println(err)
+		}
+		return steps.Result{Result: nil, Error: nil, Context: g.context}
+	}
+	result := g.runSteps(ctx)
+	if result.Error == nil {
+		if err := g.recordState(); err != nil {
+			fmt.Println(err)
+		}
+		if key != "" {
+			g.saveOutputs(key)
+		}
+	}
+	return result
+}
+
+// isUpToDate returns if every declared output exists and either has contents matching the recorded state or is newer than every input.
+func (g *Task) isUpToDate() (bool, error) {
+	for _, p := range g.outputs {
+		matches, err := glob(p)
+		if err != nil {
+			return false, err
+		}
+		if len(matches) == 0 {
+			return false, nil
+		}
+	}
+	outputs, err := globFiles(g.outputs)
+	if err != nil {
+		return false, err
+	}
+	inputs, err := globFiles(g.inputs)
+	if err != nil {
+		return false, err
+	}
+
+	// Compare content hashes against the recorded state.
+	if state, ok := loadState()[g.Name]; ok {
+		inputHashes, err := hashFiles(inputs)
+		if err != nil {
+			return false, err
+		}
+		outputHashes, err := hashFiles(outputs)
+		if err != nil {
+			return false, err
+		}
+		if sameHashes(state.Inputs, inputHashes) && sameHashes(state.Outputs, outputHashes) {
+			return true, nil
+		}
+	}
+
+	// Fall back to comparing modification times.
+	var newestInput time.Time
+	for _, file := range inputs {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		if info.ModTime().After(newestInput) {
+			newestInput = info.ModTime()
+		}
+	}
+	for _, file := range outputs {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		if !info.ModTime().After(newestInput) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// recordState stores the current hashes of the task's inputs and outputs in the StateFile.
+func (g *Task) recordState() error {
+	inputs, err := globFiles(g.inputs)
+	if err != nil {
+		return err
+	}
+	outputs, err := globFiles(g.outputs)
+	if err != nil {
+		return err
+	}
+	var state taskState
+	if state.Inputs, err = hashFiles(inputs); err != nil {
+		return err
+	}
+	if state.Outputs, err = hashFiles(outputs); err != nil {
+		return err
+	}
+
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	states := readState()
+	states[g.Name] = state
+	b, err := json.MarshalIndent(states, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(StateFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(StateFile, b, 0644)
+}
+
+func loadState() map[string]taskState {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	return readState()
+}
+
+func readState() map[string]taskState {
+	states := make(map[string]taskState)
+	b, err := os.ReadFile(StateFile)
+	if err != nil {
+		return states
+	}
+	if err := json.Unmarshal(b, &states); err != nil {
+		fmt.Println(err)
+	}
+	return states
+}
+
+// globFiles returns the sorted, de-duplicated files matched by the provided glob paths. Directories are omitted.
+func globFiles(paths []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		matches, err := glob(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			match = filepath.Clean(match)
+			if seen[match] {
+				continue
+			}
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// hashFiles returns a map of the provided files to the hex-encoded sha256 of their contents.
+func hashFiles(files []string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for _, file := range files {
+		hash, err := hashFile(file)
+		if err != nil {
+			return nil, err
+		}
+		hashes[file] = hash
+	}
+	return hashes, nil
+}
+
+func hashFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sameHashes(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
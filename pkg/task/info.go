@@ -0,0 +1,54 @@
+package task
+
+import "fmt"
+
+// Info is a machine-readable description of a task.
+type Info struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Group       string              `json:"group,omitempty"`
+	Hidden      bool                `json:"hidden,omitempty"`
+	DependsOn   []string            `json:"dependsOn,omitempty"`
+	Params      []ParamInfo         `json:"params,omitempty"`
+	Inputs      []string            `json:"inputs,omitempty"`
+	Outputs     []string            `json:"outputs,omitempty"`
+	Watch       []string            `json:"watch,omitempty"`
+	Matrix      map[string][]string `json:"matrix,omitempty"`
+	Steps       []string            `json:"steps"`
+}
+
+// ParamInfo is a machine-readable description of a task parameter.
+type ParamInfo struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default string `json:"default"`
+}
+
+// Info returns a machine-readable description of the task.
+func (g *Task) Info() Info {
+	info := Info{
+		Name:        g.Name,
+		Description: g.description,
+		Group:       g.group,
+		Hidden:      g.hidden,
+		DependsOn:   g.dependencies,
+		Inputs:      g.inputs,
+		Outputs:     g.outputs,
+		Watch:       g.watchPaths,
+		Steps:       []string{},
+	}
+	for _, p := range g.params {
+		info.Params = append(info.Params, ParamInfo{
+			Name:    p.Name,
+			Type:    p.Type(),
+			Default: p.Default,
+		})
+	}
+	if g.matrix != nil {
+		info.Matrix = g.matrix.axes
+	}
+	for _, step := range g.steps {
+		info.Steps = append(info.Steps, fmt.Sprint(step))
+	}
+	return info
+}
@@ -0,0 +1,209 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+	"github.com/kettek/gobl/pkg/shell"
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// matrix holds the axes of a task's build matrix along with the combinations it excludes and how they run.
+type matrix struct {
+	axes     map[string][]string
+	excludes []map[string]string
+	parallel bool
+	limit    int
+}
+
+// Matrix runs the task's steps once for each combination of the values of the given axes, such as GOOS and GOARCH. Each combination runs with its own copy of the task's context, in which every axis is set as an environment variable, so it can be referenced in step arguments as "${GOOS}". Combinations run in order unless MatrixParallel is given, and a summary of which combinations passed or failed is printed once they have all run.
+func (g *Task) Matrix(axes map[string][]string) *Task {
+	for name, values := range axes {
+		if !shell.IsName(name) {
+			g.errs = append(g.errs, fmt.Errorf("Matrix: invalid axis name %q", name))
+			return g
+		}
+		if len(values) == 0 {
+			g.errs = append(g.errs, fmt.Errorf("Matrix: axis %s has no values", name))
+			return g
+		}
+	}
+	if g.matrix == nil {
+		g.matrix = &matrix{}
+	}
+	g.matrix.axes = axes
+	return g
+}
+
+// Exclude skips the matrix combinations that match all of the given axis values, such as {"GOOS": "windows", "GOARCH": "arm"}.
+func (g *Task) Exclude(values map[string]string) *Task {
+	if g.matrix == nil {
+		g.matrix = &matrix{}
+	}
+	g.matrix.excludes = append(g.matrix.excludes, values)
+	return g
+}
+
+// MatrixParallel runs the task's matrix combinations at the same time, with at most limit running at once. A limit of 0 runs every combination at once.
+func (g *Task) MatrixParallel(limit int) *Task {
+	if limit < 0 {
+		g.errs = append(g.errs, fmt.Errorf("MatrixParallel: invalid limit %d", limit))
+		return g
+	}
+	if g.matrix == nil {
+		g.matrix = &matrix{}
+	}
+	g.matrix.parallel = true
+	g.matrix.limit = limit
+	return g
+}
+
+// validate checks that the matrix has axes and that its excludes only refer to their values.
+func (m *matrix) validate() error {
+	if len(m.axes) == 0 {
+		return errors.New("Exclude and MatrixParallel require a Matrix")
+	}
+	for _, exclude := range m.excludes {
+		for name, value := range exclude {
+			values, ok := m.axes[name]
+			if !ok {
+				return fmt.Errorf("Exclude: %s is not a matrix axis", name)
+			}
+			if !contains(values, value) {
+				return fmt.Errorf("Exclude: %q is not a value of matrix axis %s", value, name)
+			}
+		}
+	}
+	if len(m.combinations()) == 0 {
+		return errors.New("Matrix: every combination is excluded")
+	}
+	return nil
+}
+
+// names returns the names of the matrix's axes in sorted order.
+func (m *matrix) names() []string {
+	var names []string
+	for name := range m.axes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// combinations returns every combination of the matrix's values that is not excluded, as environment variables in the order of the axes' names. Values vary fastest in the last axis.
+func (m *matrix) combinations() [][]string {
+	combinations := [][]string{nil}
+	for _, name := range m.names() {
+		var next [][]string
+		for _, c := range combinations {
+			for _, value := range m.axes[name] {
+				next = append(next, append(append([]string(nil), c...), name+"="+value))
+			}
+		}
+		combinations = next
+	}
+	var included [][]string
+	for _, c := range combinations {
+		if !m.excluded(c) {
+			included = append(included, c)
+		}
+	}
+	return included
+}
+
+// excluded reports whether the combination matches any of the matrix's excludes.
+func (m *matrix) excluded(combination []string) bool {
+	for _, exclude := range m.excludes {
+		matches := true
+		for name, value := range exclude {
+			if !contains(combination, name+"="+value) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// runMatrix runs the block once for each combination of the task's matrix, then prints a summary of their results. Unlike loop iterations, a failing combination does not stop the others, so that the summary is complete.
+func (g *Task) runMatrix(ctx context.Context, b block) steps.Result {
+	combinations := g.matrix.combinations()
+	limit := 1
+	if g.matrix.parallel {
+		limit = g.matrix.limit
+		if limit <= 0 || limit > len(combinations) {
+			limit = len(combinations)
+		}
+	}
+	durations := make([]time.Duration, len(combinations))
+	errs := runLimited(ctx, len(combinations), limit, false, func(ctx context.Context, i int) error {
+		messages.Info(messages.MatrixCombination, colors.Info, colors.Clear, strings.Join(combinations[i], " "))
+		c := cloneContext(g.context)
+		c.AddEnv(combinations[i]...)
+		start := time.Now()
+		result := g.runBlock(ctx, b, steps.Result{Result: nil, Error: nil, Context: c})
+		durations[i] = time.Since(start)
+		return result.Error
+	})
+	g.printMatrixSummary(combinations, errs, durations)
+
+	if ctx.Err() != nil {
+		return steps.Result{Result: nil, Error: ctx.Err(), Context: g.context}
+	}
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return steps.Result{Result: nil, Error: fmt.Errorf("%d of %d matrix combinations failed", failed, len(combinations)), Context: g.context}
+	}
+	return steps.Result{Result: nil, Error: nil, Context: g.context}
+}
+
+// printMatrixSummary prints a table of the matrix's combinations and whether each passed or failed.
+func (g *Task) printMatrixSummary(combinations [][]string, errs []error, durations []time.Duration) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(g.matrix.names(), "\t")+"\tRESULT")
+	for i, c := range combinations {
+		for _, env := range c {
+			fmt.Fprint(w, env[strings.Index(env, "=")+1:]+"\t")
+		}
+		switch err := errs[i]; {
+		case err == nil:
+			fmt.Fprintf(w, "%spassed in %s%s\n", colors.Success, durations[i], colors.Clear)
+		case errors.Is(err, context.Canceled):
+			fmt.Fprintf(w, "%scancelled%s\n", colors.Notice, colors.Clear)
+		default:
+			fmt.Fprintf(w, "%sfailed%s: %s\n", colors.Error, colors.Clear, err)
+		}
+	}
+	w.Flush()
+
+	messages.Info(messages.MatrixSummary, colors.Info, g.Name, colors.Clear)
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		messages.Info("\t%s", line)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
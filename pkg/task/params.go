@@ -0,0 +1,170 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Param is a named value that can be set from the command line when running a task.
+type Param struct {
+	Name    string
+	Value   interface{} // A pointer to the parameter's value.
+	Default string
+}
+
+// Type returns the name of the parameter's type.
+func (p *Param) Type() string {
+	switch p.Value.(type) {
+	case *string:
+		return "string"
+	case *bool:
+		return "bool"
+	case *int:
+		return "int"
+	case *int64:
+		return "int64"
+	case *uint:
+		return "uint"
+	case *float64:
+		return "float64"
+	}
+	return "unknown"
+}
+
+// Parse parses the provided string according to the parameter's type.
+func (p *Param) Parse(value string) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch p.Value.(type) {
+	case *string:
+		v = value
+	case *bool:
+		v, err = strconv.ParseBool(value)
+	case *int:
+		v, err = strconv.Atoi(value)
+	case *int64:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case *uint:
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, 0)
+		v = uint(u)
+	case *float64:
+		v, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for parameter \"%s\": expected %s", value, p.Name, p.Type())
+	}
+	return v, nil
+}
+
+// Set parses the provided string according to the parameter's type and sets the parameter's value.
+func (p *Param) Set(value string) error {
+	v, err := p.Parse(value)
+	if err != nil {
+		return err
+	}
+	switch ptr := p.Value.(type) {
+	case *string:
+		*ptr = v.(string)
+	case *bool:
+		*ptr = v.(bool)
+	case *int:
+		*ptr = v.(int)
+	case *int64:
+		*ptr = v.(int64)
+	case *uint:
+		*ptr = v.(uint)
+	case *float64:
+		*ptr = v.(float64)
+	}
+	return nil
+}
+
+// Param declares a named parameter with a default value. The parameter's type is that of the default, which may be a string, bool, int, int64, uint, or float64. A pointer to one of these types may be passed to bind the parameter to a variable, in which case the variable's current value is the default.
+func (g *Task) Param(name string, def interface{}) *Task {
+	p := &Param{Name: name}
+	switch v := def.(type) {
+	case string:
+		p.Value = &v
+	case bool:
+		p.Value = &v
+	case int:
+		p.Value = &v
+	case int64:
+		p.Value = &v
+	case uint:
+		p.Value = &v
+	case float64:
+		p.Value = &v
+	case *string, *bool, *int, *int64, *uint, *float64:
+		p.Value = v
+	default:
+		g.errs = append(g.errs, fmt.Errorf("parameter \"%s\" has unsupported type %T", name, def))
+		return g
+	}
+	p.Default = fmt.Sprint(dereference(p.Value))
+	if g.GetParam(name) != nil {
+		g.errs = append(g.errs, fmt.Errorf("parameter \"%s\" is declared multiple times", name))
+		return g
+	}
+	g.params = append(g.params, p)
+	return g
+}
+
+// Arg returns a pointer to the named parameter's value. It is intended to be passed to steps such as Exec so that the parameter's value is read when the step runs.
+func (g *Task) Arg(name string) interface{} {
+	if p := g.GetParam(name); p != nil {
+		return p.Value
+	}
+	g.errs = append(g.errs, fmt.Errorf("parameter \"%s\" is not declared", name))
+	return ""
+}
+
+// GetParam returns the named parameter or nil if it does not exist.
+func (g *Task) GetParam(name string) *Param {
+	for _, p := range g.params {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Params returns the task's parameters.
+func (g *Task) Params() []*Param {
+	return g.params
+}
+
+// SetParam sets the named parameter from the provided string.
+func (g *Task) SetParam(name, value string) error {
+	p := g.GetParam(name)
+	if p == nil {
+		return fmt.Errorf("task \"%s\" has no parameter \"%s\"", g.Name, name)
+	}
+	return p.Set(value)
+}
+
+// setParamVars sets a variable in the task's context for each parameter, so that parameters can be referenced in step arguments.
+func (g *Task) setParamVars() {
+	for _, p := range g.params {
+		g.context.SetVar(p.Name, fmt.Sprint(dereference(p.Value)))
+	}
+}
+
+func dereference(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *string:
+		return *v
+	case *bool:
+		return *v
+	case *int:
+		return *v
+	case *int64:
+		return *v
+	case *uint:
+		return *v
+	case *float64:
+		return *v
+	}
+	return v
+}
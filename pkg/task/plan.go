@@ -0,0 +1,203 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// WritePlan writes what running the named task would do, including its dependencies and any tasks it runs, without running any steps.
+func WritePlan(w io.Writer, name string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	p := &planner{w: w, wd: wd}
+	return p.planTask(name, 0)
+}
+
+// planner walks tasks and their steps, keeping track of the working directory as Chdir steps would change it.
+type planner struct {
+	w     io.Writer
+	wd    string
+	stack []string
+}
+
+func (p *planner) planTask(name string, depth int) error {
+	order, err := DependencyOrder(name)
+	if err != nil {
+		return err
+	}
+	for _, n := range order {
+		if err := p.planSteps(GetTask(n), depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *planner) planSteps(g *Task, depth int) error {
+	for _, n := range p.stack {
+		if n == g.Name {
+			return fmt.Errorf("task \"%s\" runs itself", g.Name)
+		}
+	}
+	p.stack = append(p.stack, g.Name)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
+	fmt.Fprintf(p.w, strings.Repeat("\t", depth)+messages.PlannedTask+"\n", colors.Notice, colors.Clear, g.Name)
+
+	b, err := parseSteps(g.steps)
+	if err != nil {
+		return fmt.Errorf("task \"%s\": %s", g.Name, err)
+	}
+	// Variables that are known before running, such as parameters, are interpolated. References to others, such as captured results, are shown as is.
+	g.setParamVars()
+	if g.matrix != nil {
+		for _, c := range g.matrix.combinations() {
+			fmt.Fprintf(p.w, "%smatrix %s\n", strings.Repeat("\t", depth+1), strings.Join(c, " "))
+		}
+	}
+	tp := &taskPlanner{
+		planner: p,
+		result:  steps.Result{Result: nil, Error: nil, Context: g.context},
+		// Each task starts from the process's working directory.
+		wd: p.wd,
+	}
+	return tp.planBlock(b, depth+1)
+}
+
+// taskPlanner plans the steps of a single task.
+type taskPlanner struct {
+	*planner
+	result steps.Result
+	wd     string
+}
+
+func (p *taskPlanner) interpolate(s string) string {
+	return steps.InterpolateDefined(s, p.result)
+}
+
+// planBlock writes the plan of each node in the block. The steps of conditional blocks are all shown, as conditions are only evaluated when running.
+func (p *taskPlanner) planBlock(b block, depth int) error {
+	indent := strings.Repeat("\t", depth)
+	for _, n := range b {
+		switch n := n.(type) {
+		case *stepNode:
+			if err := p.planStep(n.step, depth); err != nil {
+				return err
+			}
+		case *catchNode:
+			if err := p.planBlock(block{n.node}, depth); err != nil {
+				return err
+			}
+			fmt.Fprintf(p.w, "%s%v\n", indent, n.catch)
+		case *ifNode:
+			for _, branch := range n.branches {
+				fmt.Fprintf(p.w, "%s%v\n", indent, branch.condition)
+				if err := p.planBlock(branch.body, depth+1); err != nil {
+					return err
+				}
+			}
+			if n.otherwise != nil {
+				fmt.Fprintf(p.w, "%selse\n", indent)
+				if err := p.planBlock(n.otherwise, depth+1); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(p.w, "%sendif\n", indent)
+		case *promptNode:
+			fmt.Fprintf(p.w, "%s%v\n", indent, n.prompt)
+			if n.yes != nil {
+				fmt.Fprintf(p.w, "%syes\n", indent)
+				if err := p.planBlock(n.yes, depth+1); err != nil {
+					return err
+				}
+			}
+			if n.no != nil {
+				fmt.Fprintf(p.w, "%sno\n", indent)
+				if err := p.planBlock(n.no, depth+1); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(p.w, "%send\n", indent)
+		case *forEachNode:
+			fmt.Fprintf(p.w, "%s%v\n", indent, n.loop)
+			// Each iteration starts in the working directory of the loop.
+			wd := p.wd
+			if err := p.planBlock(n.body, depth+1); err != nil {
+				return err
+			}
+			p.wd = wd
+			fmt.Fprintf(p.w, "%sendforeach\n", indent)
+		}
+	}
+	return nil
+}
+
+func (p *taskPlanner) planStep(step steps.Step, depth int) error {
+	indent := strings.Repeat("\t", depth)
+	switch step := steps.Unwrap(step).(type) {
+	case *steps.ExecStep:
+		args := step.Command()
+		for i, arg := range args {
+			args[i] = p.interpolate(arg)
+		}
+		fmt.Fprintf(p.w, "%sexec %s%s %s(in %s)%s\n", indent, strings.Join(args, " "), redirects(step), colors.Info, p.wd, colors.Clear)
+	case *steps.PipeStep:
+		var stdin string
+		if step.Stdin != nil {
+			stdin = " " + step.Stdin.String()
+		}
+		fmt.Fprintf(p.w, "%s%v%s %s(in %s)%s\n", indent, step, stdin, colors.Info, p.wd, colors.Clear)
+	case *steps.ShStep:
+		fmt.Fprintf(p.w, "%s%v %s(in %s)%s\n", indent, step, colors.Info, p.wd, colors.Clear)
+	case steps.ChdirStep:
+		p.wd = filepath.Join(p.wd, p.interpolate(step.Path))
+		fmt.Fprintf(p.w, "%schdir %s\n", indent, p.wd)
+	case steps.ExistsStep:
+		fmt.Fprintf(p.w, "%sexists %s\n", indent, filepath.Join(p.wd, p.interpolate(step.Path)))
+	case steps.EnvStep:
+		for _, arg := range step.Args {
+			fmt.Fprintf(p.w, "%senv %s\n", indent, p.interpolate(arg))
+		}
+	case steps.RunStep:
+		fmt.Fprintf(p.w, "%srun %s\n", indent, step.TaskName)
+		if err := p.planTask(step.TaskName, depth+1); err != nil {
+			return err
+		}
+	case steps.ParallelStep:
+		fmt.Fprintf(p.w, "%sparallel %s\n", indent, strings.Join(step.TaskNames, ", "))
+		for _, name := range step.TaskNames {
+			if err := p.planTask(name, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		fmt.Fprintf(p.w, "%s%v\n", indent, step)
+	}
+	return nil
+}
+
+// redirects returns a shell-like description of where an Exec step's input is read from and its output is written, if not the terminal.
+func redirects(s *steps.ExecStep) string {
+	var r string
+	if s.Stdin != nil {
+		r += " " + s.Stdin.String()
+	}
+	if s.Stdout != nil {
+		r += " " + s.Stdout.String()
+	}
+	if s.MergeStderr {
+		r += " 2>&1"
+	} else if s.Stderr != nil {
+		r += " 2" + s.Stderr.String()
+	}
+	return r
+}
@@ -0,0 +1,231 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kettek/gobl/pkg/colors"
+	"github.com/kettek/gobl/pkg/messages"
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+// MaxJobs limits how many tasks run at once, or 0 for no limit.
+var MaxJobs int
+
+// Scheduler runs tasks after their dependencies, running each task at most once. If any task fails, all other running tasks are cancelled.
+type Scheduler struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	run       func(context.Context, string) chan steps.Result
+	lock      sync.Mutex
+	scheduled map[string]*scheduledTask
+	jobs      chan struct{}
+	parent    *heldJob   // The job of the task that started the Scheduler, if any.
+	root      *Scheduler // The Scheduler of the invocation, if the Scheduler was started by one of its tasks.
+}
+
+type scheduledTask struct {
+	done   chan struct{}
+	result steps.Result
+}
+
+// NewScheduler returns a Scheduler that runs tasks with run, sharing the Scheduler of the task whose context it is given, if any.
+func NewScheduler(ctx context.Context, run func(context.Context, string) chan steps.Result) *Scheduler {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Scheduler{
+		ctx:       ctx,
+		cancel:    cancel,
+		run:       run,
+		scheduled: make(map[string]*scheduledTask),
+	}
+	if root, ok := ctx.Value(schedulerKey{}).(*Scheduler); ok {
+		s.root = root
+		s.jobs = root.jobs
+	} else if MaxJobs > 0 {
+		s.jobs = make(chan struct{}, MaxJobs)
+	}
+	if parent, ok := ctx.Value(heldJobKey{}).(*heldJob); ok {
+		s.parent = parent
+	}
+	return s
+}
+
+// Run runs the named task once all of its dependencies have completed.
+func (s *Scheduler) Run(name string) chan steps.Result {
+	result := make(chan steps.Result)
+	go func() {
+		if GetTask(name) != nil {
+			if err := checkDependencies(name); err != nil {
+				fmt.Printf(messages.FailedTask+"\n", colors.Error, name, colors.Clear, err)
+				result <- steps.Result{Result: nil, Error: err, Context: nil}
+				return
+			}
+		}
+		// The task that started the Scheduler gives up its job while it waits, so that the tasks it runs can use it.
+		if s.parent != nil {
+			s.parent.release()
+		}
+		var r steps.Result
+		if s.root != nil {
+			r = s.execute(name)
+		} else {
+			st := s.schedule(name)
+			<-st.done
+			r = st.result
+		}
+		if s.parent != nil {
+			s.parent.reacquire()
+		}
+		result <- r
+	}()
+	return result
+}
+
+// schedule returns the invocation's entry for the named task, starting it if needed.
+func (s *Scheduler) schedule(name string) *scheduledTask {
+	if s.root != nil {
+		return s.root.schedule(name)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if st, ok := s.scheduled[name]; ok {
+		return st
+	}
+	st := &scheduledTask{done: make(chan struct{})}
+	s.scheduled[name] = st
+	go func() {
+		defer close(st.done)
+		st.result = s.execute(name)
+	}()
+	return st
+}
+
+func (s *Scheduler) execute(name string) steps.Result {
+	if t := GetTask(name); t != nil {
+		var deps []*scheduledTask
+		for _, dep := range t.dependencies {
+			deps = append(deps, s.schedule(dep))
+		}
+		for i, dep := range deps {
+			select {
+			case <-dep.done:
+			case <-s.ctx.Done():
+				return steps.Result{Result: nil, Error: s.ctx.Err(), Context: nil}
+			}
+			if errors.Is(dep.result.Error, context.Canceled) {
+				return steps.Result{Result: nil, Error: dep.result.Error, Context: nil}
+			} else if dep.result.Error != nil {
+				err := fmt.Errorf(messages.FailedDependency, t.dependencies[i], dep.result.Error)
+				fmt.Printf(messages.FailedTask+"\n", colors.Error, name, colors.Clear, err)
+				return steps.Result{Result: nil, Error: err, Context: nil}
+			}
+		}
+	}
+	root := s
+	if s.root != nil {
+		root = s.root
+	}
+	ctx := context.WithValue(s.ctx, schedulerKey{}, root)
+	if s.jobs != nil {
+		select {
+		case s.jobs <- struct{}{}:
+			defer func() { <-s.jobs }()
+		case <-s.ctx.Done():
+			return steps.Result{Result: nil, Error: s.ctx.Err(), Context: nil}
+		}
+		ctx = context.WithValue(ctx, heldJobKey{}, &heldJob{jobs: s.jobs})
+	}
+	if s.ctx.Err() != nil {
+		return steps.Result{Result: nil, Error: s.ctx.Err(), Context: nil}
+	}
+	result := <-s.run(ctx, name)
+	if result.Error != nil {
+		s.cancel()
+	}
+	return result
+}
+
+type schedulerKey struct{}
+
+type heldJobKey struct{}
+
+// heldJob is the job held by a running task. While the task waits for tasks that it runs itself, the job is released so that those tasks can run even if no other jobs are free.
+type heldJob struct {
+	jobs    chan struct{}
+	lock    sync.Mutex
+	waiting int
+}
+
+// release releases the job if the task is not already waiting for other tasks.
+func (h *heldJob) release() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.waiting == 0 {
+		<-h.jobs
+	}
+	h.waiting++
+}
+
+// reacquire waits for a job to be free once the task is no longer waiting for any other tasks.
+func (h *heldJob) reacquire() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.waiting--
+	if h.waiting == 0 {
+		h.jobs <- struct{}{}
+	}
+}
+
+// DependencyOrder returns the named task's dependency graph in the order it must be run, ending with the task itself. An error is returned if a dependency does not exist or if a cycle is found.
+func DependencyOrder(name string) ([]string, error) {
+	var order []string
+	if err := walkDependencies(name, func(name string) { order = append(order, name) }); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// checkDependencies returns an error if any of the named task's dependencies do not exist or if a cycle is found.
+func checkDependencies(name string) error {
+	return walkDependencies(name, func(string) {})
+}
+
+// walkDependencies calls fn for each task in the named task's dependency graph in the order it must be run, ending with the task itself.
+func walkDependencies(name string, fn func(name string)) error {
+	var path []string
+	visited := make(map[string]bool)
+
+	var visit func(string) error
+	visit = func(name string) error {
+		for i, p := range path {
+			if p == name {
+				return fmt.Errorf(messages.DependencyCycle, strings.Join(append(path[i:], name), " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+		t := GetTask(name)
+		if t == nil {
+			if len(path) == 0 {
+				return fmt.Errorf(messages.MissingTask, name)
+			}
+			return fmt.Errorf(messages.MissingDependency, path[len(path)-1], name)
+		}
+		path = append(path, name)
+		for _, dep := range t.dependencies {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		visited[name] = true
+		fn(name)
+		return nil
+	}
+
+	return visit(name)
+}
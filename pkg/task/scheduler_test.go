@@ -0,0 +1,114 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kettek/gobl/pkg/steps"
+)
+
+func TestSchedulerRunsTasksOnce(t *testing.T) {
+	var lock sync.Mutex
+	runs := make(map[string]int)
+	run := func(ctx context.Context, name string) chan steps.Result {
+		lock.Lock()
+		runs[name]++
+		lock.Unlock()
+		result := make(chan steps.Result, 1)
+		result <- steps.Result{}
+		return result
+	}
+	s := NewScheduler(context.Background(), run)
+	<-s.Run("shared")
+	<-s.Run("shared")
+	if runs["shared"] != 1 {
+		t.Errorf("task ran %d times, want 1", runs["shared"])
+	}
+}
+
+func TestSchedulerSharesJobsWithNestedTasks(t *testing.T) {
+	defer func(n int) { MaxJobs = n }(MaxJobs)
+	MaxJobs = 1
+
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	var run func(ctx context.Context, name string) chan steps.Result
+	run = func(ctx context.Context, name string) chan steps.Result {
+		result := make(chan steps.Result)
+		go func() {
+			if name == "parent" {
+				// Like Parallel, run the children through their own Schedulers.
+				a := NewScheduler(ctx, run).Run("a")
+				b := NewScheduler(ctx, run).Run("b")
+				<-a
+				<-b
+				result <- steps.Result{}
+				return
+			}
+			lock.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			lock.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			lock.Lock()
+			running--
+			lock.Unlock()
+			result <- steps.Result{}
+		}()
+		return result
+	}
+
+	select {
+	case <-NewScheduler(context.Background(), run).Run("parent"):
+	case <-time.After(5 * time.Second):
+		t.Fatal("nested tasks deadlocked waiting for a job")
+	}
+	if maxRunning != 1 {
+		t.Errorf("%d nested tasks ran at once, want 1", maxRunning)
+	}
+}
+
+func TestSchedulerSharesDependenciesWithNestedTasks(t *testing.T) {
+	defer func(tasks []*Task) { Tasks = tasks }(Tasks)
+	Tasks = nil
+	AddTask(NewTask("gen", &testContext{}))
+	AddTask(NewTask("a", &testContext{}).DependsOn("gen"))
+	AddTask(NewTask("b", &testContext{}).DependsOn("gen"))
+	AddTask(NewTask("all", &testContext{}))
+
+	var lock sync.Mutex
+	runs := make(map[string]int)
+	var run func(ctx context.Context, name string) chan steps.Result
+	run = func(ctx context.Context, name string) chan steps.Result {
+		lock.Lock()
+		runs[name]++
+		lock.Unlock()
+		result := make(chan steps.Result)
+		go func() {
+			if name == "all" {
+				// Like Parallel, run the tasks through their own Schedulers.
+				a := NewScheduler(ctx, run).Run("a")
+				b := NewScheduler(ctx, run).Run("b")
+				<-a
+				<-b
+			} else {
+				time.Sleep(10 * time.Millisecond)
+			}
+			result <- steps.Result{}
+		}()
+		return result
+	}
+
+	s := NewScheduler(context.Background(), run)
+	<-s.Run("all")
+	<-s.Run("gen")
+	for name, want := range map[string]int{"all": 1, "a": 1, "b": 1, "gen": 1} {
+		if runs[name] != want {
+			t.Errorf("%s ran %d times, want %d", name, runs[name], want)
+		}
+	}
+}
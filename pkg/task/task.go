@@ -1,16 +1,21 @@
 package task
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"reflect"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kettek/gobl/pkg/colors"
 	"github.com/kettek/gobl/pkg/messages"
+	"github.com/kettek/gobl/pkg/shell"
 	"github.com/kettek/gobl/pkg/steps"
 
 	"github.com/radovskyb/watcher"
@@ -19,13 +24,23 @@ import (
 // Task is a named container for steps.
 type Task struct {
 	Name           string
-	running        bool
-	watcher        *watcher.Watcher
+	description    string
+	group          string
+	hidden         bool
 	watchPaths     []string
 	steps          []steps.Step
 	runChannel     chan bool
 	stopChannel    chan error
 	signalChannels []chan bool
+	cancelLock     sync.Mutex
+	cancelRun      context.CancelFunc
+	timeout        time.Duration
+	dependencies   []string
+	inputs         []string
+	outputs        []string
+	params         []*Param
+	errs           []error
+	matrix         *matrix
 	context        steps.Context
 }
 
@@ -34,14 +49,13 @@ func NewTask(name string, context steps.Context) *Task {
 	return &Task{
 		Name:        name,
 		stopChannel: make(chan error),
-		runChannel:  make(chan bool),
-		watcher:     watcher.New(),
+		runChannel:  make(chan bool, 1),
 		context:     context,
 	}
 }
 
 // TODO: We should have each step able to be regular or parallel. Either one would receive an input channel for kill/reset and return an output channel for complete/update/etc.. Parallel steps, such as Watch, would simply run, then processing would immediately continue to the next step (which might block). These parallel operations channels would be added to the Task in a separate slice.
-func (g *Task) runSteps() steps.Result {
+func (g *Task) runSteps(ctx context.Context) steps.Result {
 	// Store working directory so we can restore on close.
 	wd, err := os.Getwd()
 	if err != nil {
@@ -54,147 +68,99 @@ func (g *Task) runSteps() steps.Result {
 		}
 	}()
 
-	// Variables for Prompt functionality.
-	skipToIndex := -1
-	queryHandled := false
-
-	prevResult := steps.Result{Result: nil, Error: nil, Context: g.context}
-	for i := 0; i < len(g.steps); i++ {
-		// skipToIndex is used for "jumping"
-		if skipToIndex != -1 {
-			i = skipToIndex
-			skipToIndex = -1
-		}
-		step := g.steps[i]
-
-		result := <-step.Run(prevResult)
-		result.Context = g.context
-		catchStep := g.getFollowingCatch(i)
-		if result.Error != nil {
-			if catchStep == nil {
-				return result
-			}
-			if catchResult := <-catchStep.Run(result); catchResult.Error != nil {
-				return catchResult
-			}
-		}
-		if catchStep != nil {
-			i++
-		} else {
-			// Seems safe enough of a spot to put prompt/yes/no handling.
-			switch step.(type) {
-			case steps.PromptStep:
-				queryHandled = false
-				yes, nextYesIndex := g.getNextStep(i, steps.YesStep{})
-				no, nextNoIndex := g.getNextStep(i, steps.NoStep{})
-				_, nextPromptEndIndex := g.getNextStep(i, steps.EndStep{})
-				if result.Result == true {
-					if yes == nil {
-						skipToIndex = nextPromptEndIndex
-					} else {
-						skipToIndex = nextYesIndex
-					}
-				} else {
-					if no == nil {
-						skipToIndex = nextPromptEndIndex
-					} else {
-						skipToIndex = nextNoIndex
-					}
-				}
-			case steps.YesStep:
-				if queryHandled {
-					_, nextPromptEndIndex := g.getNextStep(i, steps.EndStep{})
-					skipToIndex = nextPromptEndIndex
-				}
-				queryHandled = true
-			case steps.NoStep:
-				if queryHandled {
-					_, nextPromptEndIndex := g.getNextStep(i, steps.EndStep{})
-					skipToIndex = nextPromptEndIndex
-				}
-				queryHandled = true
-			case steps.EndStep:
-				queryHandled = false
-			}
-		}
-		prevResult = result
-	}
-	return prevResult
-}
-
-func (g *Task) killProcesses() {
-	for _, ch := range g.context.GetProcessKillChannels() {
-		ch <- steps.Result{}
-	}
-}
-
-func (g *Task) getFollowingCatch(pos int) steps.Step {
-	if pos+1 >= len(g.steps) {
-		return nil
-	}
-	step := g.steps[pos+1]
-	switch step := step.(type) {
-	case steps.CatchStep:
-		return &step
+	b, err := parseSteps(g.steps)
+	if err != nil {
+		return steps.Result{Result: nil, Error: err, Context: g.context}
 	}
-	return nil
-}
-
-func (g *Task) getNextStep(pos int, target steps.Step) (steps.Step, int) {
-	for i := pos + 1; i < len(g.steps); i++ {
-		if i >= len(g.steps) {
-			return nil, i - 1
-		}
-		step := g.steps[i]
-		if reflect.TypeOf(target) == reflect.TypeOf(step) {
-			return step, i
-		}
+	if g.matrix != nil {
+		return g.runMatrix(ctx, b)
 	}
-	return nil, pos + 1
+	return g.runBlock(ctx, b, steps.Result{Result: nil, Error: nil, Context: g.context})
 }
 
-func (g *Task) runLoop(resultChan chan steps.Result) {
-	g.running = true
+func (g *Task) runLoop(ctx context.Context, resultChan chan steps.Result) {
 	for {
 		select {
 		case shouldExit := <-g.runChannel:
-			result := g.runSteps()
+			var runCtx context.Context
+			var cancel context.CancelFunc
+			if g.timeout > 0 {
+				runCtx, cancel = context.WithTimeout(ctx, g.timeout)
+			} else {
+				runCtx, cancel = context.WithCancel(ctx)
+			}
+			// Tasks that restart report the processes stopped by each restart.
+			var report *steps.StopReport
+			if !shouldExit || len(g.signalChannels) > 0 {
+				report = &steps.StopReport{}
+				runCtx = steps.WithStopReport(runCtx, report)
+			}
+			g.cancelLock.Lock()
+			g.cancelRun = cancel
+			g.cancelLock.Unlock()
+
+			result := g.run(runCtx)
+			if ctx.Err() == nil && runCtx.Err() == context.DeadlineExceeded {
+				result.Error = &steps.TimeoutError{Duration: g.timeout}
+			}
+
+			g.cancelLock.Lock()
+			g.cancelRun = nil
+			g.cancelLock.Unlock()
+			cancel()
+			if report != nil {
+				report.Print()
+			}
+
 			if shouldExit {
 				resultChan <- result
-				g.running = false
 				return
 			}
 		case err := <-g.stopChannel:
 			resultChan <- steps.Result{Result: nil, Error: err, Context: g.context}
-			g.running = false
+			return
+		case <-ctx.Done():
+			resultChan <- steps.Result{Result: nil, Error: ctx.Err(), Context: g.context}
 			return
 		}
 	}
 }
 
-func (g *Task) watchLoop() {
-	if len(g.watcher.WatchedFiles()) > 0 {
-		fmt.Printf(messages.WatchingTask+"\n", colors.Info, colors.Clear)
-		for k := range g.watcher.WatchedFiles() {
-			fmt.Printf("\t%s\n", k)
+func (g *Task) watchLoop(ctx context.Context) {
+	// Each execution has its own watcher, as a watcher cannot be started again once closed.
+	w := watcher.New()
+	if err := g.addWatchPaths(w); err != nil {
+		select {
+		case g.stopChannel <- err:
+		case <-ctx.Done():
+		}
+		return
+	}
+	if len(w.WatchedFiles()) > 0 {
+		messages.Info(messages.WatchingTask, colors.Info, colors.Clear)
+		for k := range w.WatchedFiles() {
+			messages.Info("\t%s", k)
 		}
 		// Watch events goroutine.
 		go func() {
 			g.runChannel <- false // Initial run
 			for {
 				select {
-				case <-g.watcher.Event:
-					// Yeah, I know this is racey.
-					if g.running {
-						if len(g.runChannel) == 0 {
-							g.kill()
-							g.runChannel <- false
-						}
+				case <-w.Event:
+					g.restart()
+				case err := <-w.Error:
+					select {
+					case g.stopChannel <- err:
+					case <-ctx.Done():
 					}
-				case err := <-g.watcher.Error:
-					g.stopChannel <- err
-				case <-g.watcher.Closed:
-					g.stopChannel <- nil
+				case <-w.Closed:
+					select {
+					case g.stopChannel <- nil:
+					case <-ctx.Done():
+					}
+					return
+				case <-ctx.Done():
+					w.Close()
 					return
 				}
 			}
@@ -202,8 +168,8 @@ func (g *Task) watchLoop() {
 
 		// Watch goroutine.
 		go func() {
-			if err := g.watcher.Start(time.Millisecond * 100); err != nil {
-				g.watcher.Close()
+			if err := w.Start(time.Millisecond * 100); err != nil {
+				w.Close()
 			}
 		}()
 	} else {
@@ -211,59 +177,63 @@ func (g *Task) watchLoop() {
 	}
 }
 
-func (g *Task) kill() {
-	for i := 0; i < len(g.steps); i++ {
-		step := g.steps[i]
-		switch step := step.(type) {
-		case steps.RunStep:
-			g2 := GetTask(step.TaskName)
-			if g2 != nil && g2.running {
-				g2.killProcesses()
-			}
-		case steps.ParallelStep:
-			for _, taskName := range step.TaskNames {
-				g2 := GetTask(taskName)
-				if g2 != nil && g2.running {
-					g2.killProcesses()
-				}
-			}
-		}
+// restart cancels the current run of the task, if any, and queues another run. If a run is already queued, no additional run is queued.
+func (g *Task) restart() {
+	g.cancelLock.Lock()
+	if g.cancelRun != nil {
+		messages.Info(messages.RestartingTask, colors.Notice, colors.Clear, g.Name)
+		g.cancelRun()
+	}
+	g.cancelLock.Unlock()
+	select {
+	case g.runChannel <- false:
+	default:
 	}
 }
 
-// Execute runs the given Task.
-func (g *Task) Execute() chan steps.Result {
+// Execute runs the given Task. Cancelling the context stops any running steps, including those of tasks started by the Task.
+func (g *Task) Execute(ctx context.Context) chan steps.Result {
 	result := make(chan steps.Result)
 
-	go g.runLoop(result)
+	go g.runLoop(ctx, result)
 
-	go g.watchLoop()
+	go g.watchLoop(ctx)
 	return result
 }
 
-// Watch sets up a variadic number of glob paths to watch. It supports double-star "**" globbing.
+// Watch sets up a variadic number of glob paths to watch. It supports double-star "**" globbing. Variable references in the paths are interpolated and the paths are matched when the task runs.
 func (g *Task) Watch(paths ...string) *Task {
-	for _, path := range paths {
-		if strings.Contains(path, "**") {
-			matches, err := doubleGlob(path)
-			if err != nil {
-				fmt.Println(err)
-			}
-			g.watchPaths = append(g.watchPaths, matches...)
-		} else {
-			matches, err := filepath.Glob(path)
-			if err != nil {
+	g.watchPaths = append(g.watchPaths, paths...)
+	return g
+}
+
+// addWatchPaths interpolates and matches the task's watch paths, adding the matching files to the watcher.
+func (g *Task) addWatchPaths(w *watcher.Watcher) error {
+	g.setParamVars()
+	for _, path := range g.watchPaths {
+		path, err := steps.Interpolate(path, steps.Result{Result: nil, Error: nil, Context: g.context})
+		if err != nil {
+			return err
+		}
+		matches, err := glob(path)
+		if err != nil {
+			fmt.Println(err)
+		}
+		for _, file := range matches {
+			if err := w.Add(file); err != nil {
 				fmt.Println(err)
 			}
-			g.watchPaths = append(g.watchPaths, matches...)
 		}
 	}
-	for _, file := range g.watchPaths {
-		if err := g.watcher.Add(file); err != nil {
-			fmt.Println(err)
-		}
+	return nil
+}
+
+// glob returns the matches for a glob path, using doubleGlob if it contains a "**".
+func glob(p string) ([]string, error) {
+	if strings.Contains(p, "**") {
+		return doubleGlob(p)
 	}
-	return g
+	return filepath.Glob(p)
 }
 
 func doubleGlob(p string) ([]string, error) {
@@ -305,7 +275,7 @@ func doubleGlob(p string) ([]string, error) {
 	return matches, nil
 }
 
-// Signaler redirects a given signal to kill steps in the task.
+// Signaler redirects a given signal to cancel the task's running steps and run the task again.
 func (g *Task) Signaler(t ...os.Signal) *Task {
 	ch := make(chan bool)
 	g.signalChannels = append(g.signalChannels, ch)
@@ -318,18 +288,261 @@ func (g *Task) Signaler(t ...os.Signal) *Task {
 			case <-ch:
 				signal.Reset(t...)
 				run = false
-				fmt.Println("RESET")
 			case <-sigChan:
-				g.kill()
-				fmt.Println("KILL")
-				g.runChannel <- false
+				g.restart()
 			}
 		}
 	}()
 	return g
 }
 
-// Catch catches the error of any preceding steps.
+// Describe sets the task's description, shown when listing tasks.
+func (g *Task) Describe(description string) *Task {
+	g.description = description
+	return g
+}
+
+// Description returns the task's description.
+func (g *Task) Description() string {
+	return g.description
+}
+
+// Group sets the category the task is listed under.
+func (g *Task) Group(name string) *Task {
+	g.group = name
+	return g
+}
+
+// GroupName returns the category the task is listed under.
+func (g *Task) GroupName() string {
+	return g.group
+}
+
+// Hidden omits the task from task listings unless all tasks are requested. Hidden tasks can still be run.
+func (g *Task) Hidden() *Task {
+	g.hidden = true
+	return g
+}
+
+// IsHidden returns if the task is omitted from task listings.
+func (g *Task) IsHidden() bool {
+	return g.hidden
+}
+
+// DependsOn declares tasks that must complete successfully before this task runs. Each dependency is run only once per invocation, even if multiple tasks depend on it.
+func (g *Task) DependsOn(taskNames ...string) *Task {
+	g.dependencies = append(g.dependencies, taskNames...)
+	return g
+}
+
+// Inputs declares the files, as glob paths, that the task's outputs are built from. It supports double-star "**" globbing.
+func (g *Task) Inputs(paths ...string) *Task {
+	g.inputs = append(g.inputs, paths...)
+	return g
+}
+
+// Outputs declares the files, as glob paths, that the task produces. If all outputs are up to date with the task's inputs, the task's steps are skipped. It supports double-star "**" globbing.
+func (g *Task) Outputs(paths ...string) *Task {
+	g.outputs = append(g.outputs, paths...)
+	return g
+}
+
+// Timeout limits how long each run of the task may take, adhering to https://pkg.go.dev/time#ParseDuration. If the time limit is reached, the task's steps are cancelled and the task fails with a steps.TimeoutError. As the whole task is stopped, its Catch handlers do not run; use StepTimeout to handle a time limit in Catch.
+func (g *Task) Timeout(duration string) *Task {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		g.errs = append(g.errs, err)
+		return g
+	}
+	g.timeout = d
+	return g
+}
+
+// StepTimeout limits how long the preceding step may take, adhering to https://pkg.go.dev/time#ParseDuration. If the time limit is reached, the step is cancelled and fails with a steps.TimeoutError, which can be checked for in Catch with errors.Is(err, steps.ErrTimeout).
+func (g *Task) StepTimeout(duration string) *Task {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		g.errs = append(g.errs, err)
+		return g
+	}
+	g.wrapLastStep("StepTimeout", func(s steps.Step) steps.Step {
+		return steps.TimeoutStep{Step: s, Duration: d}
+	})
+	return g
+}
+
+// Retry runs the preceding step again, up to the given number of times, if it fails. The backoff determines how long to wait before each retry and may be nil to retry immediately. If any retryIf predicates are given, the step is only retried if one of them returns true for the step's error.
+func (g *Task) Retry(retries int, backoff steps.Backoff, retryIf ...func(error) bool) *Task {
+	if retries < 1 {
+		g.errs = append(g.errs, fmt.Errorf("Retry requires at least 1 retry"))
+		return g
+	}
+	g.wrapLastStep("Retry", func(s steps.Step) steps.Step {
+		return steps.RetryStep{Step: s, Retries: retries, Backoff: backoff, RetryIf: retryIf}
+	})
+	return g
+}
+
+// GracePeriod sets how long the preceding Exec step's process is given to exit after being asked to stop before it is forcibly killed, adhering to https://pkg.go.dev/time#ParseDuration. A duration of "0s" kills the process immediately.
+func (g *Task) GracePeriod(duration string) *Task {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		g.errs = append(g.errs, err)
+		return g
+	}
+	if s := g.lastExec("GracePeriod"); s != nil {
+		s.GracePeriod = d
+	}
+	return g
+}
+
+// Interactive runs the preceding Exec step in gobl's process group rather than its own, so that it can read from the terminal, such as sudo or ssh asking for a password. Stopping the step then only stops the command itself, not any processes it started.
+func (g *Task) Interactive() *Task {
+	if s := g.lastExec("Interactive"); s != nil {
+		s.Interactive = true
+	}
+	return g
+}
+
+// AllowExitCodes treats the given exit codes of the preceding Exec step as success, such as 1 for grep finding no matches.
+func (g *Task) AllowExitCodes(codes ...int) *Task {
+	if s := g.lastExec("AllowExitCodes"); s != nil {
+		s.ExitCodes = append(s.ExitCodes, codes...)
+	}
+	return g
+}
+
+// Stdin writes text to the standard input of the preceding Exec or Pipe step.
+func (g *Task) Stdin(text string) *Task {
+	g.setStdin("Stdin", &steps.Input{Text: text})
+	return g
+}
+
+// StdinFile reads the standard input of the preceding Exec or Pipe step from the file at path, relative to the working directory.
+func (g *Task) StdinFile(path string) *Task {
+	g.setStdin("StdinFile", &steps.Input{Path: path})
+	return g
+}
+
+// StdinResult writes the result of the step before the preceding Exec or Pipe step to its standard input, such as the output of a previous Exec.
+func (g *Task) StdinResult() *Task {
+	g.setStdin("StdinResult", &steps.Input{Result: true})
+	return g
+}
+
+// setStdin sets the standard input of the most recently added step if it is an Exec or Pipe step.
+func (g *Task) setStdin(modifier string, in *steps.Input) {
+	if len(g.steps) > 0 {
+		switch s := steps.Unwrap(g.steps[len(g.steps)-1]).(type) {
+		case *steps.ExecStep:
+			s.Stdin = in
+			return
+		case *steps.PipeStep:
+			s.Stdin = in
+			return
+		}
+	}
+	g.errs = append(g.errs, fmt.Errorf("%s must follow an Exec or Pipe step", modifier))
+}
+
+// Stdout writes the preceding Exec step's standard output to w instead of the terminal.
+func (g *Task) Stdout(w io.Writer) *Task {
+	if w == nil {
+		g.errs = append(g.errs, fmt.Errorf("Stdout: nil writer, use Discard to discard output"))
+		return g
+	}
+	if s := g.lastExec("Stdout"); s != nil {
+		s.Stdout = &steps.Output{Writer: w}
+	}
+	return g
+}
+
+// Stderr writes the preceding Exec step's standard error to w instead of the terminal.
+func (g *Task) Stderr(w io.Writer) *Task {
+	if w == nil {
+		g.errs = append(g.errs, fmt.Errorf("Stderr: nil writer, use Discard to discard output"))
+		return g
+	}
+	if s := g.lastExec("Stderr"); s != nil {
+		s.Stderr = &steps.Output{Writer: w}
+	}
+	return g
+}
+
+// StdoutFile writes the preceding Exec step's standard output to the file at path, relative to the working directory, instead of the terminal. The file is truncated unless appending.
+func (g *Task) StdoutFile(path string, appending bool) *Task {
+	if s := g.lastExec("StdoutFile"); s != nil {
+		s.Stdout = &steps.Output{Path: path, Append: appending}
+	}
+	return g
+}
+
+// StderrFile writes the preceding Exec step's standard error to the file at path, relative to the working directory, instead of the terminal. The file is truncated unless appending.
+func (g *Task) StderrFile(path string, appending bool) *Task {
+	if s := g.lastExec("StderrFile"); s != nil {
+		s.Stderr = &steps.Output{Path: path, Append: appending}
+	}
+	return g
+}
+
+// MergeStderr writes the preceding Exec step's standard error to the same destination as its standard output, like 2>&1. Both streams are captured into the result's Stdout.
+func (g *Task) MergeStderr() *Task {
+	if s := g.lastExec("MergeStderr"); s != nil {
+		s.MergeStderr = true
+	}
+	return g
+}
+
+// Discard discards the preceding Exec step's output rather than writing it to the terminal. The output is still captured into the step's result.
+func (g *Task) Discard() *Task {
+	if s := g.lastExec("Discard"); s != nil {
+		s.Stdout = steps.DiscardOutput
+		s.Stderr = steps.DiscardOutput
+	}
+	return g
+}
+
+// StopSignal sets the signal used to ask the preceding Exec step's process to stop, such as SigInterrupt. If the process has not exited once its GracePeriod has passed, it is killed.
+func (g *Task) StopSignal(sig os.Signal) *Task {
+	if s := g.lastExec("StopSignal"); s != nil {
+		s.StopSignal = sig
+	}
+	return g
+}
+
+// Capture stores the result of the preceding step in the named variable, so that later steps can reference it as "${NAME}" or "{{.NAME}}". The output of an Exec step is stored with its trailing newlines removed.
+func (g *Task) Capture(name string) *Task {
+	if !shell.IsName(name) {
+		g.errs = append(g.errs, fmt.Errorf("Capture: invalid variable name %q", name))
+		return g
+	}
+	g.wrapLastStep("Capture", func(s steps.Step) steps.Step {
+		return steps.CaptureStep{Step: s, Name: name}
+	})
+	return g
+}
+
+// lastExec returns the most recently added step if it is an Exec step.
+func (g *Task) lastExec(modifier string) *steps.ExecStep {
+	if len(g.steps) > 0 {
+		if s, ok := steps.Unwrap(g.steps[len(g.steps)-1]).(*steps.ExecStep); ok {
+			return s
+		}
+	}
+	g.errs = append(g.errs, fmt.Errorf("%s must follow an Exec step", modifier))
+	return nil
+}
+
+// wrapLastStep replaces the most recently added step with the result of wrap.
+func (g *Task) wrapLastStep(modifier string, wrap func(steps.Step) steps.Step) {
+	if len(g.steps) == 0 {
+		g.errs = append(g.errs, fmt.Errorf("%s must follow a step", modifier))
+		return
+	}
+	g.steps[len(g.steps)-1] = wrap(g.steps[len(g.steps)-1])
+}
+
+// Catch catches the error of the preceding step or block. If f returns nil, the error is handled and the task continues as if the step had succeeded, even if it was the task's last step. If f returns an error, the task fails with it.
 func (g *Task) Catch(f func(error) error) *Task {
 	g.steps = append(g.steps, steps.CatchStep{
 		Func: f,
@@ -353,6 +566,24 @@ func (g *Task) Run(taskName string) *Task {
 	return g
 }
 
+// InheritEnv makes the tasks run by the preceding Run or Parallel step start with this task's environment, including changes made by Env steps. Otherwise, they start with the process's environment.
+func (g *Task) InheritEnv() *Task {
+	if len(g.steps) > 0 {
+		switch s := g.steps[len(g.steps)-1].(type) {
+		case steps.RunStep:
+			s.InheritEnv = true
+			g.steps[len(g.steps)-1] = s
+			return g
+		case steps.ParallelStep:
+			s.InheritEnv = true
+			g.steps[len(g.steps)-1] = s
+			return g
+		}
+	}
+	g.errs = append(g.errs, fmt.Errorf("InheritEnv must follow a Run or Parallel step"))
+	return g
+}
+
 // Parallel runs tasks in parallel.
 func (g *Task) Parallel(taskNames ...string) *Task {
 	g.steps = append(g.steps, steps.ParallelStep{
@@ -363,13 +594,47 @@ func (g *Task) Parallel(taskNames ...string) *Task {
 
 // Exec executes a command.
 func (g *Task) Exec(args ...interface{}) *Task {
-	g.steps = append(g.steps, steps.ExecStep{
-		Args: args,
+	g.steps = append(g.steps, &steps.ExecStep{
+		Args:        args,
+		GracePeriod: steps.DefaultGracePeriod,
 	})
 	return g
 }
 
-// Env sets environment variables.
+// Pipe runs the given commands at once, connecting each command's output to the next command's input like a shell pipeline. Like a shell with pipefail set, the step fails if any of the commands fail.
+func (g *Task) Pipe(commands ...[]interface{}) *Task {
+	p := &steps.PipeStep{}
+	for _, args := range commands {
+		if len(args) == 0 {
+			g.errs = append(g.errs, fmt.Errorf("Pipe command has no arguments"))
+			continue
+		}
+		p.Commands = append(p.Commands, &steps.ExecStep{
+			Args:        args,
+			GracePeriod: steps.DefaultGracePeriod,
+		})
+	}
+	if len(p.Commands) == 0 {
+		g.errs = append(g.errs, fmt.Errorf("Pipe has no commands"))
+	}
+	g.steps = append(g.steps, p)
+	return g
+}
+
+// Sh runs a shell script, such as "go build -o bin/app ./cmd/app && ./bin/app", with a built-in interpreter so that it runs the same on every platform. See the shell package for the supported syntax.
+func (g *Task) Sh(script string) *Task {
+	parsed, err := shell.Parse(script)
+	if err != nil {
+		g.errs = append(g.errs, fmt.Errorf("Sh %q: %w", script, err))
+	}
+	g.steps = append(g.steps, &steps.ShStep{
+		Source: script,
+		Script: parsed,
+	})
+	return g
+}
+
+// Env sets environment variables, in the form "KEY=value". Values can reference variables as "${NAME}", such as the current item of a ForEach loop.
 func (g *Task) Env(args ...string) *Task {
 	g.steps = append(g.steps, steps.EnvStep{
 		Args: args,
@@ -377,6 +642,39 @@ func (g *Task) Env(args ...string) *Task {
 	return g
 }
 
+// EnvFile sets environment variables from dotenv files, such as ".env" and ".env.local". Files are read in order so that later files take precedence, and missing files are skipped.
+func (g *Task) EnvFile(paths ...string) *Task {
+	g.steps = append(g.steps, steps.EnvFileStep{
+		Paths: paths,
+	})
+	return g
+}
+
+// RequireEnvFile is like EnvFile, but fails if any of the files do not exist.
+func (g *Task) RequireEnvFile(paths ...string) *Task {
+	g.steps = append(g.steps, steps.EnvFileStep{
+		Paths:    paths,
+		Required: true,
+	})
+	return g
+}
+
+// Unenv removes environment variables, such as ones inherited from the process.
+func (g *Task) Unenv(names ...string) *Task {
+	g.steps = append(g.steps, steps.UnenvStep{
+		Names: names,
+	})
+	return g
+}
+
+// ClearEnv removes all environment variables except for the named ones, such as "PATH" and "HOME".
+func (g *Task) ClearEnv(keep ...string) *Task {
+	g.steps = append(g.steps, steps.ClearEnvStep{
+		Keep: keep,
+	})
+	return g
+}
+
 // Chdir changes the current directory.
 func (g *Task) Chdir(path string) *Task {
 	g.steps = append(g.steps, steps.ChdirStep{
@@ -409,6 +707,228 @@ func (g *Task) Print(args ...interface{}) *Task {
 	return g
 }
 
+// If begins a block of steps that only run if the function returns true. The block is ended with EndIf and may be divided with ElseIf and Else.
+func (g *Task) If(f func(steps.Context) bool) *Task {
+	g.steps = append(g.steps, steps.IfStep{
+		Condition:   func(r steps.Result) (bool, error) { return f(r.Context), nil },
+		Description: "func",
+	})
+	return g
+}
+
+// IfExists begins a block of steps that only run if the given file or directory exists, relative to the working directory.
+func (g *Task) IfExists(path string) *Task {
+	g.steps = append(g.steps, steps.IfStep{
+		Condition:   existsCondition(path),
+		Description: "exists " + path,
+	})
+	return g
+}
+
+// IfEnv begins a block of steps that only run if the named environment variable is set to a non-empty value, such as "CI". If values are given, the variable must be set to one of them.
+func (g *Task) IfEnv(name string, values ...string) *Task {
+	description := "env " + name
+	if len(values) > 0 {
+		description += " in " + strings.Join(values, ", ")
+	}
+	g.steps = append(g.steps, steps.IfStep{
+		Condition:   envCondition(name, values),
+		Description: description,
+	})
+	return g
+}
+
+// IfOS begins a block of steps that only run on one of the given operating systems, as named by runtime.GOOS.
+func (g *Task) IfOS(names ...string) *Task {
+	g.steps = append(g.steps, steps.IfStep{
+		Condition: func(r steps.Result) (bool, error) {
+			for _, name := range names {
+				if name == runtime.GOOS {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		Description: "os " + strings.Join(names, ", "),
+	})
+	return g
+}
+
+// ElseIf begins a block of steps within an If block that only run if the preceding conditions are false and the function returns true.
+func (g *Task) ElseIf(f func(steps.Context) bool) *Task {
+	g.steps = append(g.steps, steps.ElseIfStep{
+		Condition:   func(r steps.Result) (bool, error) { return f(r.Context), nil },
+		Description: "func",
+	})
+	return g
+}
+
+// Else begins a block of steps within an If block that only run if the preceding conditions are false.
+func (g *Task) Else() *Task {
+	g.steps = append(g.steps, steps.ElseStep{})
+	return g
+}
+
+// EndIf signifies the end of an If block.
+func (g *Task) EndIf() *Task {
+	g.steps = append(g.steps, steps.EndIfStep{})
+	return g
+}
+
+// existsCondition returns a condition that is true if the path exists. Variable references in the path are interpolated.
+func existsCondition(path string) steps.Condition {
+	return func(r steps.Result) (bool, error) {
+		p, err := steps.Interpolate(path, r)
+		if err != nil {
+			return false, err
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(r.Context.WorkingDirectory(), p)
+		}
+		if _, err := os.Stat(p); err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// envCondition returns a condition that is true if the named environment variable is non-empty and, if values are given, set to one of them.
+func envCondition(name string, values []string) steps.Condition {
+	return func(r steps.Result) (bool, error) {
+		v, _ := steps.LookupEnv(r.Context.GetEnv(), name)
+		if v == "" {
+			return false, nil
+		}
+		if len(values) == 0 {
+			return true, nil
+		}
+		for _, value := range values {
+			if v == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// ForEach begins a block of steps that runs once for each of the given items, ending with EndForEach. The current item is held in the ITEM variable, or the one named with As. Variable references in the items are interpolated.
+func (g *Task) ForEach(items ...string) *Task {
+	g.steps = append(g.steps, steps.ForEachStep{
+		Items: func(r steps.Result) ([]string, error) {
+			out := make([]string, len(items))
+			for i, item := range items {
+				v, err := steps.Interpolate(item, r)
+				if err != nil {
+					return nil, err
+				}
+				out[i] = v
+			}
+			return out, nil
+		},
+		Description: strings.Join(items, ", "),
+		Var:         steps.DefaultForEachVar,
+	})
+	return g
+}
+
+// ForEachGlob begins a block of steps that runs once for each file or directory matching the glob pattern, relative to the working directory. It supports double-star "**" globbing.
+func (g *Task) ForEachGlob(pattern string) *Task {
+	g.steps = append(g.steps, steps.ForEachStep{
+		Items: func(r steps.Result) ([]string, error) {
+			p, err := steps.Interpolate(pattern, r)
+			if err != nil {
+				return nil, err
+			}
+			wd := r.Context.WorkingDirectory()
+			abs := filepath.IsAbs(p)
+			if !abs {
+				p = filepath.Join(wd, p)
+			}
+			matches, err := glob(p)
+			if err != nil {
+				return nil, err
+			}
+			for i, m := range matches {
+				if abs {
+					break
+				}
+				if matches[i], err = filepath.Rel(wd, m); err != nil {
+					return nil, err
+				}
+			}
+			sort.Strings(matches)
+			return matches, nil
+		},
+		Description: "glob " + pattern,
+		Var:         steps.DefaultForEachVar,
+	})
+	return g
+}
+
+// ForEachLine begins a block of steps that runs once for each non-empty line of the previous step's result, such as the output of an Exec step.
+func (g *Task) ForEachLine() *Task {
+	g.steps = append(g.steps, steps.ForEachStep{
+		Items: func(r steps.Result) ([]string, error) {
+			var lines []string
+			for _, line := range strings.Split(steps.ResultString(r.Result), "\n") {
+				if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+					lines = append(lines, line)
+				}
+			}
+			return lines, nil
+		},
+		Description: "line of result",
+		Var:         steps.DefaultForEachVar,
+	})
+	return g
+}
+
+// As sets the name of the variable that holds the current item of the preceding ForEach.
+func (g *Task) As(name string) *Task {
+	if !shell.IsName(name) {
+		g.errs = append(g.errs, fmt.Errorf("As: invalid variable name %q", name))
+		return g
+	}
+	g.updateForEach("As", func(s *steps.ForEachStep) {
+		s.Var = name
+	})
+	return g
+}
+
+// InParallel runs the iterations of the preceding ForEach at the same time, with at most limit running at once. A limit of 0 runs every iteration at once.
+func (g *Task) InParallel(limit int) *Task {
+	if limit < 0 {
+		g.errs = append(g.errs, fmt.Errorf("InParallel: invalid limit %d", limit))
+		return g
+	}
+	g.updateForEach("InParallel", func(s *steps.ForEachStep) {
+		s.Parallel = true
+		s.Limit = limit
+	})
+	return g
+}
+
+// EndForEach signifies the end of a ForEach block.
+func (g *Task) EndForEach() *Task {
+	g.steps = append(g.steps, steps.EndForEachStep{})
+	return g
+}
+
+// updateForEach applies update to the most recently added step if it is a ForEach step.
+func (g *Task) updateForEach(modifier string, update func(*steps.ForEachStep)) {
+	if len(g.steps) > 0 {
+		if s, ok := g.steps[len(g.steps)-1].(steps.ForEachStep); ok {
+			update(&s)
+			g.steps[len(g.steps)-1] = s
+			return
+		}
+	}
+	g.errs = append(g.errs, fmt.Errorf("%s must follow a ForEach step", modifier))
+}
+
 // Prompt prompts (Y/N) using the passed value or the result of the previous task.
 func (g *Task) Prompt(v string) *Task {
 	g.steps = append(g.steps, steps.PromptStep{
@@ -38,3 +38,24 @@ func getTaskIndex(name string) int {
 	}
 	return -1
 }
+
+// Validate checks that every task is defined correctly, including that its blocks, such as If and Prompt, are balanced, that its matrix excludes refer to its axes, that every task's dependencies exist, and that there are no dependency cycles.
+func Validate() error {
+	for _, t := range Tasks {
+		if len(t.errs) > 0 {
+			return fmt.Errorf("task \"%s\": %s", t.Name, t.errs[0])
+		}
+		if _, err := parseSteps(t.steps); err != nil {
+			return fmt.Errorf("task \"%s\": %s", t.Name, err)
+		}
+		if t.matrix != nil {
+			if err := t.matrix.validate(); err != nil {
+				return fmt.Errorf("task \"%s\": %s", t.Name, err)
+			}
+		}
+		if err := checkDependencies(t.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package gobl
+
+import "github.com/kettek/gobl/pkg/steps"
+
+// Retry helpers, for use with Task.Retry.
+var (
+	FixedBackoff       = steps.FixedBackoff
+	ExponentialBackoff = steps.ExponentialBackoff
+	RetryOnExitCodes   = steps.RetryOnExitCodes
+)